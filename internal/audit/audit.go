@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Entry is a single line of an audit log. Each entry includes the
+// hash of the previous entry, so editing or removing a line breaks
+// the chain for every entry after it.
+type Entry struct {
+	Seq      uint64          `json:"seq"`
+	PrevHash string          `json:"prev_hash"`
+	Hash     string          `json:"hash"`
+	Record   json.RawMessage `json:"record"`
+}
+
+// ComputeHash hashes the sequence, previous hash and the raw record.
+func ComputeHash(seq uint64, prevHash string, record []byte) string {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatUint(seq, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(prevHash))
+	h.Write([]byte{0})
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Chain produces entries with monotonically increasing sequence
+// numbers, each linked to the previous entry.
+type Chain struct {
+	seq      uint64
+	prevHash string
+}
+
+// Resume continues the chain after the provided entry.
+func (c *Chain) Resume(e Entry) {
+	c.seq = e.Seq
+	c.prevHash = e.Hash
+}
+
+func (c *Chain) Seq() uint64 {
+	return c.seq
+}
+
+// Next appends record to the chain and returns the encoded entry,
+// without a trailing newline.
+func (c *Chain) Next(record []byte) ([]byte, error) {
+	record = bytes.TrimSpace(record)
+	if !json.Valid(record) {
+		return nil, fmt.Errorf("audit record must be valid json")
+	}
+
+	e := Entry{
+		Seq:      c.seq + 1,
+		PrevHash: c.prevHash,
+		Record:   record,
+	}
+	e.Hash = ComputeHash(e.Seq, e.PrevHash, e.Record)
+
+	bs, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	c.seq = e.Seq
+	c.prevHash = e.Hash
+	return bs, nil
+}
+
+// LastEntry returns the last entry of an audit log, or nil if the
+// log is empty.
+func LastEntry(r io.Reader) (*Entry, error) {
+	var last []byte
+	scanner := newScanner(r)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if last == nil {
+		return nil, nil
+	}
+
+	var e Entry
+	if err := json.Unmarshal(last, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return scanner
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func newLog(t *testing.T, records ...string) []string {
+	var c Chain
+	var lines []string
+	for _, r := range records {
+		bs, err := c.Next([]byte(r))
+		assert.NoError(t, err)
+		lines = append(lines, string(bs))
+	}
+	return lines
+}
+
+func verify(lines []string) *Verifier {
+	v := &Verifier{}
+	v.Verify("test.log", strings.NewReader(strings.Join(lines, "\n")+"\n"))
+	return v
+}
+
+func violations(v *Verifier) []string {
+	var vs []string
+	for _, vl := range v.Violations() {
+		vs = append(vs, vl.String())
+	}
+	return vs
+}
+
+func TestChain_Next_InvalidJSON(t *testing.T) {
+	var c Chain
+	_, err := c.Next([]byte("core.users.total value=1"))
+	assert.EqualError(t, err, "audit record must be valid json")
+	assert.Equal(t, uint64(0), c.Seq())
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	records := []string{`{"value":1}`, `{"value":2}`, `{"value":3}`}
+
+	testCases := []struct {
+		name       string
+		tamper     func([]string) []string
+		violations []string
+	}{
+		{
+			name:   "valid",
+			tamper: func(ls []string) []string { return ls },
+		},
+		{
+			name: "edited",
+			tamper: func(ls []string) []string {
+				ls[1] = strings.Replace(ls[1], `"value":2`, `"value":20`, 1)
+				return ls
+			},
+			violations: []string{
+				"test.log:2: seq 2: hash mismatch, entry was modified",
+			},
+		},
+		{
+			name: "removed",
+			tamper: func(ls []string) []string {
+				return []string{ls[0], ls[2]}
+			},
+			violations: []string{
+				"test.log:2: seq 3: expected seq 2, entries were removed or reordered",
+			},
+		},
+		{
+			name: "removed_first",
+			tamper: func(ls []string) []string {
+				return ls[1:]
+			},
+			violations: []string{
+				"test.log:1: chain starts at seq 2, expected 1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := verify(tc.tamper(newLog(t, records...)))
+			assert.Equal(t, tc.violations, violations(v))
+			assert.Equal(t, len(tc.violations) == 0, v.Valid())
+		})
+	}
+}
+
+func TestVerifier_Verify_Head(t *testing.T) {
+	lines := newLog(t, `{"value":1}`, `{"value":2}`, `{"value":3}`)
+	head := verify(lines).LastHash()
+
+	// entries appended after the head are accepted
+	v := &Verifier{Head: head}
+	v.Verify("test.log", strings.NewReader(strings.Join(newLog(t, `{"value":1}`, `{"value":2}`, `{"value":3}`, `{"value":4}`), "\n")))
+	assert.True(t, v.Valid())
+
+	// a truncated chain is still linked, only the head detects it
+	truncated := lines[:2]
+	assert.True(t, verify(truncated).Valid())
+
+	v = &Verifier{Head: head}
+	v.Verify("test.log", strings.NewReader(strings.Join(truncated, "\n")))
+	assert.False(t, v.Valid())
+	assert.Equal(t, []string{
+		"head: entry " + head + " not found, entries were removed from the end of the chain",
+	}, violations(v))
+}
+
+func TestLastEntry_ResumesChain(t *testing.T) {
+	lines := newLog(t, `{"value":1}`, `{"value":2}`)
+
+	e, err := LastEntry(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), e.Seq)
+
+	var c Chain
+	c.Resume(*e)
+	bs, err := c.Next([]byte(`{"value":3}`))
+	assert.NoError(t, err)
+
+	v := &Verifier{}
+	all := strings.Join(append(lines, string(bs)), "\n")
+	assert.NoError(t, v.Verify("test.log", bytes.NewBufferString(all)))
+	assert.True(t, v.Valid())
+	assert.Equal(t, uint64(3), v.LastSeq())
+}
@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Violation describes an entry which does not match the chain.
+type Violation struct {
+	Source string
+	Line   int
+	Reason string
+}
+
+func (v Violation) String() string {
+	if v.Line == 0 {
+		return fmt.Sprintf("%s: %s", v.Source, v.Reason)
+	}
+	return fmt.Sprintf("%s:%d: %s", v.Source, v.Line, v.Reason)
+}
+
+// Verifier checks one or more audit logs, in order, as a single chain.
+// A chain only proves the entries it contains, entries removed from
+// its end are detected by verifying against a previously recorded
+// Head.
+type Verifier struct {
+	// AllowPartial accepts a chain which does not start at the first
+	// sequence, ie: when older rotated logs have been removed.
+	AllowPartial bool
+	// Head is the hash of an entry the chain must contain, ie: the
+	// LAST_HASH reported by a previous verification.
+	Head string
+
+	headFound  bool
+	entries    uint64
+	started    bool
+	seq        uint64
+	prevHash   string
+	violations []Violation
+}
+
+func (v *Verifier) Entries() uint64 {
+	return v.entries
+}
+
+func (v *Verifier) LastSeq() uint64 {
+	return v.seq
+}
+
+func (v *Verifier) LastHash() string {
+	return v.prevHash
+}
+
+func (v *Verifier) Violations() []Violation {
+	if v.Head != "" && !v.headFound {
+		return append(v.violations[:len(v.violations):len(v.violations)], Violation{
+			Source: "head",
+			Reason: fmt.Sprintf("entry %s not found, entries were removed from the end of the chain", v.Head),
+		})
+	}
+	return v.violations
+}
+
+func (v *Verifier) Valid() bool {
+	return len(v.Violations()) == 0
+}
+
+func (v *Verifier) violation(source string, line int, format string, args ...any) {
+	v.violations = append(v.violations, Violation{
+		Source: source,
+		Line:   line,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// Verify reads every entry from r and checks that its hash is correct
+// and that it links to the previous entry. Verification continues
+// after a violation, resynchronizing on the offending entry, so every
+// tampered region is reported.
+func (v *Verifier) Verify(source string, r io.Reader) error {
+	scanner := newScanner(r)
+	line := 0
+
+	for scanner.Scan() {
+		line++
+		bs := bytes.TrimSpace(scanner.Bytes())
+		if len(bs) == 0 {
+			continue
+		}
+
+		var e Entry
+		if err := json.Unmarshal(bs, &e); err != nil {
+			v.violation(source, line, "unable to parse entry: %s", err)
+			continue
+		}
+		v.entries++
+
+		if h := ComputeHash(e.Seq, e.PrevHash, e.Record); h != e.Hash {
+			v.violation(source, line, "seq %d: hash mismatch, entry was modified", e.Seq)
+		}
+
+		switch {
+		case !v.started:
+			if e.Seq != 1 && !v.AllowPartial {
+				v.violation(source, line, "chain starts at seq %d, expected 1", e.Seq)
+			}
+			if e.Seq == 1 && e.PrevHash != "" {
+				v.violation(source, line, "seq 1: unexpected prev_hash")
+			}
+		case e.Seq != v.seq+1:
+			v.violation(source, line, "seq %d: expected seq %d, entries were removed or reordered", e.Seq, v.seq+1)
+		case e.PrevHash != v.prevHash:
+			v.violation(source, line, "seq %d: prev_hash does not match previous entry", e.Seq)
+		}
+
+		if v.Head != "" && e.Hash == v.Head {
+			v.headFound = true
+		}
+
+		v.started = true
+		v.seq = e.Seq
+		v.prevHash = e.Hash
+	}
+
+	return scanner.Err()
+}
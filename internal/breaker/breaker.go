@@ -0,0 +1,204 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+	"time"
+)
+
+var meter = otel.Meter("latte-breaker")
+
+const (
+	defaultFailures = 5
+	defaultCooldown = time.Minute
+)
+
+// ErrOpen is returned instead of calling through an open breaker.
+var ErrOpen = errors.New("circuit breaker open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+type Config struct {
+	// Failures is the number of consecutive failures which open the
+	// breaker.
+	Failures int
+	// Cooldown is how long the breaker stays open before a trial call
+	// is let through.
+	Cooldown time.Duration
+}
+
+func (c Config) Validate() error {
+	if c.Failures < 0 {
+		return fmt.Errorf("circuit_breaker failures: %d must be positive", c.Failures)
+	}
+	if c.Cooldown < 0 {
+		return fmt.Errorf("circuit_breaker cooldown: %s must be positive", c.Cooldown)
+	}
+	return nil
+}
+
+func (c *Config) SetDefaults() {
+	if c.Failures == 0 {
+		c.Failures = defaultFailures
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = defaultCooldown
+	}
+}
+
+type Option func(*Breaker)
+
+func WithNow(fn func() time.Time) Option {
+	return func(b *Breaker) {
+		b.now = fn
+	}
+}
+
+// Breaker stops calling a failing dependency. It opens after the
+// configured consecutive failures and half-opens after the cooldown,
+// letting a single trial call decide if it closes or opens again. A
+// nil breaker always allows calls.
+type Breaker struct {
+	config Config
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool
+
+	registration metric.Registration
+
+	now func() time.Time
+}
+
+// Allow returns ErrOpen when a call should be skipped.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.config.Cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.trial = true
+		return nil
+	case StateHalfOpen:
+		// only a single trial call is let through
+		if b.trial {
+			return ErrOpen
+		}
+		b.trial = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Record reports the result of an allowed call.
+func (b *Breaker) Record(err error) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+
+	if err == nil {
+		b.state = StateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.config.Failures {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// register observes the breaker state as a gauge: 0 closed, 1 open,
+// 2 half open.
+func (b *Breaker) register(attrs []attribute.KeyValue) error {
+	set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+	state, err := meter.Int64ObservableGauge("breaker.state")
+	if err != nil {
+		return err
+	}
+
+	b.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveInt64(state, int64(b.State()), set)
+		return nil
+	}, state)
+	return err
+}
+
+// Close stops observing the breaker state, so breakers of replaced or
+// removed collectors are no longer reported.
+func (b *Breaker) Close() error {
+	if b == nil || b.registration == nil {
+		return nil
+	}
+	return b.registration.Unregister()
+}
+
+func New(c Config, attrs []attribute.KeyValue, opts ...Option) (*Breaker, error) {
+	c.SetDefaults()
+
+	b := &Breaker{
+		config: c,
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	if err := b.register(attrs); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
@@ -0,0 +1,102 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"os"
+	"testing"
+	"time"
+)
+
+// reader collects the breaker gauges, the global meter provider can
+// only be set once per process.
+var reader = sdkmetric.NewManualReader()
+
+func TestMain(m *testing.M) {
+	otel.SetMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)))
+	os.Exit(m.Run())
+}
+
+func TestBreaker_OpensAndHalfOpens(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b, err := New(Config{
+		Failures: 2,
+		Cooldown: time.Minute,
+	}, nil, WithNow(func() time.Time { return now }))
+	assert.NoError(t, err)
+
+	failure := errors.New("connection refused")
+
+	assert.NoError(t, b.Allow())
+	b.Record(failure)
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.NoError(t, b.Allow())
+	b.Record(failure)
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	// a single trial call is allowed after the cooldown
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	// a failed trial opens the breaker again
+	b.Record(failure)
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, ErrOpen, b.Allow())
+
+	// a successful trial closes it
+	now = now.Add(time.Minute)
+	assert.NoError(t, b.Allow())
+	b.Record(nil)
+	assert.Equal(t, StateClosed, b.State())
+	assert.NoError(t, b.Allow())
+}
+
+func TestBreaker_Nil(t *testing.T) {
+	var b *Breaker
+	assert.NoError(t, b.Allow())
+	b.Record(errors.New("failed"))
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_Close_StopsObservingState(t *testing.T) {
+	points := func() int {
+		var rm metricdata.ResourceMetrics
+		assert.NoError(t, reader.Collect(context.Background(), &rm))
+		n := 0
+		for _, sm := range rm.ScopeMetrics {
+			for _, m := range sm.Metrics {
+				g, ok := m.Data.(metricdata.Gauge[int64])
+				if !ok || m.Name != "breaker.state" {
+					continue
+				}
+				for _, dp := range g.DataPoints {
+					if v, _ := dp.Attributes.Value("breaker.target"); v.AsString() == "sink.closed" {
+						n++
+					}
+				}
+			}
+		}
+		return n
+	}
+
+	b, err := New(Config{}, []attribute.KeyValue{
+		attribute.String("breaker.target", "sink.closed"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, points())
+
+	assert.NoError(t, b.Close())
+	assert.Equal(t, 0, points())
+
+	var nilBreaker *Breaker
+	assert.NoError(t, nilBreaker.Close())
+}
@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+func NewAuditCmd() *cobra.Command {
+	var auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "audit ",
+		Long:  ``,
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println("audit")
+		},
+	}
+	return auditCmd
+}
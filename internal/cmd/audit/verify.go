@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/turbolytics/latte/internal/audit"
+	"os"
+)
+
+func NewVerifyCmd() *cobra.Command {
+	var allowPartial bool
+	var head string
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify [audit log]...",
+		Short: "Verify the hash chain of audit sink logs",
+		Long: `Verify the hash chain of one or more audit sink logs. Multiple logs,
+such as rotated backups, are verified in the order provided as a single chain.
+
+The chain proves the entries it contains, but entries removed from the end
+of a log leave a valid chain behind. Record the reported LAST_HASH and pass
+it as --head on the next verification to detect truncation.`,
+		Args:         cobra.MinimumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := &audit.Verifier{
+				AllowPartial: allowPartial,
+				Head:         head,
+			}
+
+			for _, p := range args {
+				f, err := os.Open(p)
+				if err != nil {
+					return err
+				}
+				err = v.Verify(p, f)
+				f.Close()
+				if err != nil {
+					return fmt.Errorf("unable to read audit log %q: %w", p, err)
+				}
+			}
+
+			for _, violation := range v.Violations() {
+				fmt.Println(violation)
+			}
+
+			fmt.Printf("ENTRIES=%d\n", v.Entries())
+			fmt.Printf("LAST_SEQ=%d\n", v.LastSeq())
+			fmt.Printf("LAST_HASH=%s\n", v.LastHash())
+			fmt.Printf("VALID=%t\n", v.Valid())
+
+			if !v.Valid() {
+				return errors.New("audit log chain is invalid")
+			}
+			return nil
+		},
+	}
+
+	verifyCmd.Flags().BoolVarP(&allowPartial, "allow-partial", "", false, "Allow a chain which does not start at the first entry, ie: after rotated logs were removed")
+	verifyCmd.Flags().StringVarP(&head, "head", "", "", "Hash of an entry the chain must contain, ie: the LAST_HASH of a previous verification")
+
+	return verifyCmd
+}
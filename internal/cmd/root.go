@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/turbolytics/latte/internal/cmd/audit"
 	"github.com/turbolytics/latte/internal/cmd/config"
 	"os"
 )
@@ -13,7 +14,12 @@ func init() {
 		config.NewValidateCmd(),
 		config.NewInvokeCmd(),
 	)
+	auditCmd := audit.NewAuditCmd()
+	auditCmd.AddCommand(
+		audit.NewVerifyCmd(),
+	)
 	rootCmd.AddCommand(
+		auditCmd,
 		configCmd,
 		NewRunCmd(),
 	)
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"github.com/spf13/cobra"
 	"github.com/turbolytics/latte/internal/collector/initializer"
 	"github.com/turbolytics/latte/internal/invoker"
@@ -11,17 +12,31 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func NewRunCmd() *cobra.Command {
 	var configsGlob string
 	var otelExporter string
+	var gracePeriod time.Duration
+	var health service.HealthConfig
+	var reloadInterval time.Duration
+	var maxConcurrency int
+	var groupLimits map[string]int
+	var httpAddr string
+	var admin bool
 
 	var runCmd = &cobra.Command{
 		Use:   "run",
 		Short: "Run collector daemon",
 		Long:  ``,
-		Run: func(cmd *cobra.Command, args []string) {
+		// an unclean shutdown exits non-zero without printing usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
 			// Create resource.
 			res, err := obs.NewResource()
 			if err != nil {
@@ -50,8 +65,11 @@ func NewRunCmd() *cobra.Command {
 			logger, _ := zap.NewProduction()
 			defer logger.Sync() // flushes buffer, if any
 
-			if obs.Exporter(otelExporter) == obs.ExporterPrometheus {
-				go obs.ServeMetrics(logger, ":12223")
+			// the http server only runs for prometheus metrics or the
+			// admin api, the handlers are registered before it starts.
+			metrics := obs.Exporter(otelExporter) == obs.ExporterPrometheus
+			if metrics {
+				obs.HandleMetrics()
 			}
 
 			logger.Info(
@@ -59,50 +77,93 @@ func NewRunCmd() *cobra.Command {
 				zap.String("path", configsGlob),
 			)
 
-			// initialize all collectors in the path
-			collectors, err := initializer.NewCollectorsFromGlob(
-				configsGlob,
-				initializer.RootWithLogger(logger),
+			// invocation all collectors at their desired intervals
+			s, err := service.NewService(
+				nil,
+				service.WithLogger(logger),
+				service.WithGracePeriod(gracePeriod),
+				service.WithHealth(health),
+				service.WithMaxConcurrency(maxConcurrency),
+				service.WithGroupLimits(groupLimits),
 			)
 			if err != nil {
 				panic(err)
 			}
 
-			var invokers []*invoker.Invoker
-			for _, coll := range collectors {
-				i, err := invoker.New(coll,
-					invoker.WithLogger(logger),
+			load := func(bs []byte, validate bool) (*invoker.Invoker, error) {
+				coll, err := initializer.NewCollector(
+					bs,
+					initializer.WithJustValidation(validate),
+					initializer.RootWithLogger(logger),
 				)
 				if err != nil {
-					panic(err)
+					return nil, err
 				}
-				invokers = append(invokers, i)
+				return invoker.New(coll,
+					invoker.WithLogger(logger),
+				)
+			}
+
+			// initialize all collectors in the path, an invalid config
+			// fails startup but is only rejected on reload.
+			reloader := service.NewReloader(
+				configsGlob,
+				load,
+				s,
+				service.ReloaderWithLogger(logger),
+			)
+			if err := reloader.Reload(); err != nil {
+				panic(err)
 			}
 			logger.Info(
 				"initialized invokers",
-				zap.Int("num_invokers", len(invokers)),
+				zap.Int("num_invokers", len(s.Collectors())),
 			)
 
-			// invocation all collectors at their desired intervals
-			s, err := service.NewService(
-				invokers,
-				service.WithLogger(logger),
-			)
-			if err != nil {
-				panic(err)
+			if metrics || admin {
+				if admin {
+					s.HandleAdmin(http.DefaultServeMux)
+				}
+				s.HandleHealth(http.DefaultServeMux)
+				go obs.Serve(logger, httpAddr)
 			}
 
-			defer s.Shutdown()
+			ctx, stop := signal.NotifyContext(
+				context.Background(),
+				syscall.SIGINT,
+				syscall.SIGTERM,
+			)
+			defer stop()
 
-			ctx := context.Background()
-			if err := s.Run(ctx); err != nil {
-				panic(err)
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			defer signal.Stop(hup)
+			go reloader.Watch(ctx, reloadInterval, hup)
+
+			runErr := s.Run(ctx)
+			// restore the default signal handling, a second signal
+			// terminates immediately.
+			stop()
+
+			if err := s.Shutdown(); err != nil {
+				logger.Error("unclean shutdown", zap.Error(err))
+				return errors.Join(runErr, err)
 			}
+			return runErr
 		},
 	}
 
 	runCmd.Flags().StringVarP(&configsGlob, "configs", "c", "", "Path to config directory")
 	runCmd.Flags().StringVarP(&otelExporter, "otel-exporter", "", "prometheus", "Opentelemetry exporter: 'console', prometheus")
+	runCmd.Flags().DurationVarP(&gracePeriod, "shutdown-grace-period", "", 30*time.Second, "Time to wait for running invocations on shutdown")
+	runCmd.Flags().IntVarP(&health.FailureThreshold, "health-failure-threshold", "", 3, "Consecutive failed invocations after which a collector is unhealthy")
+	runCmd.Flags().IntVarP(&health.MissedRuns, "health-missed-runs", "", 3, "Scheduled runs a collector may miss without succeeding before it is unhealthy")
+	runCmd.Flags().IntVarP(&health.MaxUnhealthy, "health-max-unhealthy", "", 1, "Number of unhealthy collectors at which /healthz fails")
+	runCmd.Flags().DurationVarP(&reloadInterval, "reload-interval", "", 30*time.Second, "How often to check the configs for changes, 0 only reloads on SIGHUP")
+	runCmd.Flags().IntVarP(&maxConcurrency, "max-concurrent-invocations", "", 0, "Maximum invocations running at once across all collectors, 0 is unlimited")
+	runCmd.Flags().StringToIntVarP(&groupLimits, "source-group-limit", "", nil, "Maximum invocations running at once per source group, ie: warehouse=2")
+	runCmd.Flags().StringVarP(&httpAddr, "http-addr", "", "localhost:12223", "Address serving metrics, health checks and the admin api")
+	runCmd.Flags().BoolVarP(&admin, "admin", "", false, "Serve the admin api, which is unauthenticated, on --http-addr")
 	runCmd.MarkFlagRequired("config")
 
 	return runCmd
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/sink"
 	"go.uber.org/zap"
+	"time"
 )
 
 type Type string
@@ -17,6 +19,9 @@ const (
 type Collector struct {
 	Type               Type
 	InvocationStrategy invoker.TypeStrategy `yaml:"invocation_strategy"`
+	// Timeout bounds each invocation, including the source and all
+	// sinks.
+	Timeout time.Duration
 }
 
 func (c Collector) Validate() error {
@@ -29,6 +34,10 @@ func (c Collector) Validate() error {
 	if _, ok := vs[c.InvocationStrategy]; !ok {
 		return fmt.Errorf("unknown strategy: %q", c.InvocationStrategy)
 	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("collector timeout: %s must be positive", c.Timeout)
+	}
 	return nil
 }
 
@@ -38,6 +47,31 @@ func (c *Collector) SetDefaults() {
 	}
 }
 
+// DeadLetter configures the sink receiving the records a collector
+// could not deliver.
+type DeadLetter struct {
+	Policy invoker.DeadLetterPolicy
+	Sink   sink.Config
+}
+
+func (d DeadLetter) Validate() error {
+	if err := d.Policy.Validate(); err != nil {
+		return err
+	}
+
+	if d.Sink.Type == "" {
+		return fmt.Errorf("dead_letter requires a sink %q", "type")
+	}
+
+	return d.Sink.Validate()
+}
+
+func (d *DeadLetter) SetDefaults() {
+	if d.Policy == "" {
+		d.Policy = invoker.DeadLetterPolicyFail
+	}
+}
+
 // Root contains top level collector configuration
 // required for initializing more concrete collectors.
 type Root struct {
@@ -6,10 +6,21 @@ import (
 	"github.com/turbolytics/latte/internal/invoker"
 	"github.com/turbolytics/latte/internal/sink"
 	"github.com/turbolytics/latte/internal/sink/console"
+	"github.com/turbolytics/latte/internal/sink/database"
+	"github.com/turbolytics/latte/internal/sink/elasticsearch"
+	"github.com/turbolytics/latte/internal/sink/fields"
 	"github.com/turbolytics/latte/internal/sink/file"
+	"github.com/turbolytics/latte/internal/sink/filter"
 	"github.com/turbolytics/latte/internal/sink/http"
 	"github.com/turbolytics/latte/internal/sink/kafka"
+	mongodbSink "github.com/turbolytics/latte/internal/sink/mongodb"
+	"github.com/turbolytics/latte/internal/sink/nats"
+	"github.com/turbolytics/latte/internal/sink/otlp"
+	promSink "github.com/turbolytics/latte/internal/sink/prometheus"
 	s3Sink "github.com/turbolytics/latte/internal/sink/s3"
+	"github.com/turbolytics/latte/internal/sink/statsd"
+	"github.com/turbolytics/latte/internal/sink/wal"
+	"github.com/turbolytics/latte/internal/sink/warehouse"
 	"github.com/turbolytics/latte/internal/source"
 	"github.com/turbolytics/latte/internal/source/metric/mongodb"
 	"github.com/turbolytics/latte/internal/source/metric/postgres"
@@ -30,23 +41,83 @@ func NewSink(c sink.Config, l *zap.Logger, validate bool) (invoker.Sinker, error
 	switch c.Type {
 	case sink.TypeConsole:
 		s, err = console.NewFromGenericConfig(c.Config)
+	case sink.TypeElasticsearch:
+		s, err = elasticsearch.NewFromGenericConfig(
+			c.Config,
+			elasticsearch.WithLogger(l),
+		)
 	case sink.TypeFile:
 		s, err = file.NewFromGenericConfig(
 			c.Config,
 			validate,
+			file.WithLogger(l),
 		)
 	case sink.TypeHTTP:
 		s, err = http.NewFromGenericConfig(
 			c.Config,
 			http.WithLogger(l),
 		)
+	case sink.TypeInfluxDB:
+		s, err = http.NewInfluxDBFromGenericConfig(
+			c.Config,
+			http.WithLogger(l),
+		)
 	case sink.TypeKafka:
 		s, err = kafka.NewFromGenericConfig(c.Config)
+	case sink.TypeMongoDB:
+		s, err = mongodbSink.NewFromGenericConfig(
+			context.Background(),
+			c.Config,
+			validate,
+			mongodbSink.WithLogger(l),
+		)
+	case sink.TypeNATS:
+		s, err = nats.NewFromGenericConfig(
+			c.Config,
+			validate,
+			nats.WithLogger(l),
+		)
+	case sink.TypeOTLP:
+		s, err = otlp.NewFromGenericConfig(
+			c.Config,
+			validate,
+			otlp.WithLogger(l),
+		)
+	case sink.TypePostgres:
+		s, err = database.NewPostgresFromGenericConfig(
+			c.Config,
+			validate,
+			database.WithLogger(l),
+		)
+	case sink.TypePrometheus:
+		s, err = promSink.NewFromGenericConfig(
+			c.Config,
+			validate,
+			promSink.WithLogger(l),
+		)
 	case sink.TypeS3:
 		s, err = s3Sink.NewFromGenericConfig(
 			c.Config,
 			s3Sink.WithLogger(l),
 		)
+	case sink.TypeSQL:
+		s, err = database.NewFromGenericConfig(
+			c.Config,
+			validate,
+			database.WithLogger(l),
+		)
+	case sink.TypeStatsD:
+		s, err = statsd.NewFromGenericConfig(
+			c.Config,
+			validate,
+			statsd.WithLogger(l),
+		)
+	case sink.TypeWarehouse:
+		s, err = warehouse.NewFromGenericConfig(
+			c.Config,
+			validate,
+			warehouse.WithLogger(l),
+		)
 	default:
 		return nil, fmt.Errorf("sink type: %q not supported", c.Type)
 	}
@@ -55,17 +126,47 @@ func NewSink(c sink.Config, l *zap.Logger, validate bool) (invoker.Sinker, error
 
 func NewSinks(cs map[string]sink.Config, l *zap.Logger, validate bool) (map[string]invoker.Sinker, error) {
 	sinks := make(map[string]invoker.Sinker)
+	router := sink.NewRouter(cs)
 
 	for k, conf := range cs {
 		i, err := NewSink(conf, l, validate)
 		if err != nil {
 			return nil, err
 		}
+
+		if conf.Buffer != nil && !validate {
+			i, err = wal.New(i, *conf.Buffer, wal.WithLogger(l))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// fields are applied last so filters match the original record
+		if conf.Fields != nil {
+			i = fields.New(i, *conf.Fields)
+		}
+
+		if conf.Filter != nil || conf.RouteBy != nil {
+			i = filter.New(i, newMatchFunc(conf, router))
+		}
+
 		sinks[k] = i
 	}
 	return sinks, nil
 }
 
+func newMatchFunc(c sink.Config, router *sink.Router) filter.MatchFunc {
+	return func(m map[string]any) bool {
+		if c.Filter != nil && !c.Filter.Match(m) {
+			return false
+		}
+		if c.RouteBy != nil && !router.Match(*c.RouteBy, m) {
+			return false
+		}
+		return true
+	}
+}
+
 func NewSourcer(sc source.Config, l *zap.Logger, validate bool) (invoker.Sourcer, error) {
 	var err error
 	var s invoker.Sourcer
@@ -2,6 +2,7 @@ package initializer
 
 import (
 	"github.com/turbolytics/latte/internal/collector/metric"
+	"github.com/turbolytics/latte/internal/invoker"
 	"github.com/turbolytics/latte/internal/schedule"
 	"github.com/turbolytics/latte/internal/transform"
 	"go.uber.org/zap"
@@ -32,6 +33,18 @@ func NewMetricCollectorFromConfig(bs []byte, validate bool, l *zap.Logger) (*met
 		return nil, err
 	}
 
+	var deadLetter *invoker.DeadLetter
+	if conf.DeadLetter != nil {
+		dl, err := NewSink(conf.DeadLetter.Sink, l, validate)
+		if err != nil {
+			return nil, err
+		}
+		deadLetter = &invoker.DeadLetter{
+			Sinker: dl,
+			Policy: conf.DeadLetter.Policy,
+		}
+	}
+
 	sourcer, err := NewSourcer(
 		conf.Source,
 		l,
@@ -49,10 +62,11 @@ func NewMetricCollectorFromConfig(bs []byte, validate bool, l *zap.Logger) (*met
 		metric.WithValidation(validate),
 		metric.WithSchedule(sch),
 		metric.WithSinks(sinks),
+		metric.WithDeadLetter(deadLetter),
 		metric.WithSourcer(sourcer),
 		metric.WithStateStore(stateStore),
 		metric.WithTransformer(transform.Noop{}),
 	)
 
-	return coll, nil
+	return coll, err
 }
@@ -22,6 +22,7 @@ func TestNewConfigFromFile(t *testing.T) {
 		{"postgres.fileaudit.yaml"},
 		{"postgres.http.yaml"},
 		{"postgres.kafka.yaml"},
+		{"postgres.kafka.routed.yaml"},
 		{"postgres.s3.yaml"},
 		{"postgres.stdout.yaml"},
 		{"prometheus.fileaudit.yaml"},
@@ -46,5 +47,5 @@ func TestNewConfigsFromGlob(t *testing.T) {
 		WithJustValidation(true),
 	)
 	assert.NoError(t, err)
-	assert.Equal(t, 8, len(cs))
+	assert.Equal(t, 9, len(cs))
 }
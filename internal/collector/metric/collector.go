@@ -2,11 +2,16 @@ package metric
 
 import (
 	"fmt"
+	"github.com/turbolytics/latte/internal/breaker"
 	"github.com/turbolytics/latte/internal/invoker"
 	"github.com/turbolytics/latte/internal/metric"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/schedule"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"sort"
+	"time"
 )
 
 type Transformer struct {
@@ -41,6 +46,11 @@ type Collector struct {
 	logger      *zap.Logger
 	schedule    schedule.Schedule
 	sinks       map[string]invoker.Sinker
+	deadLetter  *invoker.DeadLetter
+	retry       *retry.Policy
+	sinkRetry   map[string]*retry.Policy
+	breaker     *breaker.Breaker
+	sinkBreaker map[string]*breaker.Breaker
 	stateStore  invoker.Storer
 	sourcer     invoker.Sourcer
 	transformer invoker.Transformer
@@ -55,14 +65,50 @@ func (c *Collector) InvocationStrategy() invoker.TypeStrategy {
 	return c.config.Collector.InvocationStrategy
 }
 
-func (c *Collector) Sinks() []invoker.Sinker {
-	var sinks []invoker.Sinker
-	for _, sink := range c.sinks {
-		sinks = append(sinks, sink)
+// Sinks returns the sinks ordered by name.
+func (c *Collector) Sinks() []invoker.Sink {
+	var names []string
+	for name := range c.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sinks []invoker.Sink
+	for _, name := range names {
+		policy, ok := c.sinkRetry[name]
+		if !ok {
+			policy = c.retry
+		}
+
+		sinks = append(sinks, invoker.Sink{
+			Name:     name,
+			Required: c.config.Sinks[name].IsRequired(),
+			Retry:    policy,
+			Breaker:  c.sinkBreaker[name],
+			Sinker:   c.sinks[name],
+		})
 	}
 	return sinks
 }
 
+func (c *Collector) DeadLetter() *invoker.DeadLetter {
+	return c.deadLetter
+}
+
+func (c *Collector) Timeout() time.Duration {
+	return c.config.Collector.Timeout
+}
+
+func (c *Collector) Breaker() *breaker.Breaker {
+	return c.breaker
+}
+
+// Retry is the retry policy of the source, and of each sink which
+// does not override it.
+func (c *Collector) Retry() *retry.Policy {
+	return c.retry
+}
+
 func (c *Collector) Schedule() invoker.Schedule {
 	return c.schedule
 }
@@ -71,6 +117,10 @@ func (c *Collector) Sourcer() invoker.Sourcer {
 	return c.sourcer
 }
 
+func (c *Collector) SourceGroup() string {
+	return c.config.Source.Group
+}
+
 func (c *Collector) Storer() invoker.Storer {
 	return c.stateStore
 }
@@ -95,6 +145,12 @@ func WithSinks(ss map[string]invoker.Sinker) Option {
 	}
 }
 
+func WithDeadLetter(dl *invoker.DeadLetter) Option {
+	return func(c *Collector) {
+		c.deadLetter = dl
+	}
+}
+
 func WithSourcer(s invoker.Sourcer) Option {
 	return func(c *Collector) {
 		c.sourcer = s
@@ -128,12 +184,64 @@ func WithLogger(l *zap.Logger) Option {
 	}
 }
 
+// initBreakers creates a breaker for the source and each sink, each
+// sink may override the collector breaker configuration.
+func (c *Collector) initBreakers() error {
+	newBreaker := func(bc breaker.Config, target string) (*breaker.Breaker, error) {
+		return breaker.New(bc, []attribute.KeyValue{
+			attribute.String("collector.name", c.config.Name),
+			attribute.String("target", target),
+		})
+	}
+
+	var err error
+	if c.config.CircuitBreaker != nil {
+		if c.breaker, err = newBreaker(*c.config.CircuitBreaker, "source"); err != nil {
+			return err
+		}
+	}
+
+	for name, s := range c.config.Sinks {
+		bc := c.config.CircuitBreaker
+		if s.CircuitBreaker != nil {
+			bc = s.CircuitBreaker
+		}
+		if bc == nil {
+			continue
+		}
+		if c.sinkBreaker[name], err = newBreaker(*bc, "sink."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func NewCollector(conf *config, opts ...Option) (*Collector, error) {
 	c := &Collector{
-		config: conf,
+		config:      conf,
+		sinkRetry:   make(map[string]*retry.Policy),
+		sinkBreaker: make(map[string]*breaker.Breaker),
 	}
+
+	if conf.Retry != nil {
+		c.retry = retry.New(*conf.Retry)
+	}
+
+	for name, s := range conf.Sinks {
+		if s.Retry != nil {
+			c.sinkRetry[name] = retry.New(*s.Retry)
+		}
+	}
+
 	for _, opt := range opts {
 		opt(c)
 	}
+
+	// breakers register gauges, validated collectors are never closed
+	if !c.validate {
+		if err := c.initBreakers(); err != nil {
+			return nil, err
+		}
+	}
 	return c, nil
 }
@@ -1,8 +1,10 @@
 package metric
 
 import (
+	"github.com/turbolytics/latte/internal/breaker"
 	collconf "github.com/turbolytics/latte/internal/collector/config"
 	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/schedule"
 	"github.com/turbolytics/latte/internal/sink"
 	"github.com/turbolytics/latte/internal/source"
@@ -33,7 +35,12 @@ type config struct {
 	Schedule   schedule.Config
 	Source     source.Config
 	Sinks      map[string]sink.Config
-	StateStore state.Config `yaml:"state_store"`
+	DeadLetter *collconf.DeadLetter `yaml:"dead_letter"`
+	Retry      *retry.Config
+	// CircuitBreaker applies to the source, and to each sink which does
+	// not override it.
+	CircuitBreaker *breaker.Config `yaml:"circuit_breaker"`
+	StateStore     state.Config    `yaml:"state_store"`
 
 	logger *zap.Logger
 	// validate will skip initializing network dependencies
@@ -43,6 +50,10 @@ type config struct {
 func defaults(c *config) error {
 	(&c.Collector).SetDefaults()
 
+	if c.DeadLetter != nil {
+		c.DeadLetter.SetDefaults()
+	}
+
 	return nil
 }
 
@@ -53,6 +64,22 @@ func validate(c config) error {
 		c.StateStore,
 	}
 
+	for _, s := range c.Sinks {
+		validaters = append(validaters, s)
+	}
+
+	if c.DeadLetter != nil {
+		validaters = append(validaters, c.DeadLetter)
+	}
+
+	if c.Retry != nil {
+		validaters = append(validaters, c.Retry)
+	}
+
+	if c.CircuitBreaker != nil {
+		validaters = append(validaters, c.CircuitBreaker)
+	}
+
 	for _, v := range validaters {
 		if err := v.Validate(); err != nil {
 			return err
@@ -2,6 +2,7 @@ package encoding
 
 import (
 	"bytes"
+	"github.com/turbolytics/latte/internal/encoding/influx"
 	"github.com/turbolytics/latte/internal/encoding/json"
 	"github.com/turbolytics/latte/internal/encoding/parquet"
 )
@@ -11,6 +12,7 @@ type Type string
 const (
 	TypeParquet Type = "parquet"
 	TypeJSON    Type = "json"
+	TypeInflux  Type = "influx"
 )
 
 type Config struct {
@@ -32,6 +34,8 @@ func NewEncoder(c Config) (Encoder, error) {
 	switch c.Type {
 	case TypeParquet:
 		e, err = parquet.NewFromGenericConfig(c.Config)
+	case TypeInflux:
+		e, err = influx.NewFromGenericConfig(c.Config)
 	default:
 		e, err = json.NewFromGenericConfig(c.Config)
 	}
@@ -0,0 +1,169 @@
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/record"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Precision string
+
+const (
+	PrecisionNanoseconds  Precision = "ns"
+	PrecisionMicroseconds Precision = "us"
+	PrecisionMilliseconds Precision = "ms"
+	PrecisionSeconds      Precision = "s"
+)
+
+type config struct {
+	Precision Precision
+}
+
+// Influx renders records using the InfluxDB line protocol:
+//
+//	<name>[,<tag>=<value>...] value=<value> <timestamp>
+//
+// The timestamp is taken from the record window when present,
+// otherwise from the record timestamp. Tags with empty values are
+// dropped, line protocol does not allow them.
+type Influx struct {
+	buf    io.Writer
+	config config
+}
+
+func (i *Influx) Flush() error {
+	return nil
+}
+
+func (i *Influx) Close() error {
+	return nil
+}
+
+func (i *Influx) Init(buf *bytes.Buffer) error {
+	i.buf = buf
+	return nil
+}
+
+func (i *Influx) Write(d any) error {
+	m, ok := d.(map[string]any)
+	if !ok {
+		return fmt.Errorf("influx encoding requires a map, received: %T", d)
+	}
+
+	name, _ := m["name"].(string)
+	if name == "" {
+		return fmt.Errorf("influx encoding requires a %q", "name")
+	}
+
+	value, err := toFloat(m["value"])
+	if err != nil {
+		return err
+	}
+
+	var tags []string
+	for k, v := range m {
+		if !strings.HasPrefix(k, record.TagPrefix) {
+			continue
+		}
+		tv := fmt.Sprint(v)
+		if tv == "" {
+			continue
+		}
+		tags = append(tags, fmt.Sprintf(
+			"%s=%s",
+			tagEscaper.Replace(strings.TrimPrefix(k, record.TagPrefix)),
+			tagEscaper.Replace(tv),
+		))
+	}
+	sort.Strings(tags)
+
+	var b bytes.Buffer
+	b.WriteString(measurementEscaper.Replace(name))
+	for _, t := range tags {
+		b.WriteByte(',')
+		b.WriteString(t)
+	}
+	b.WriteByte(' ')
+	b.WriteString(tagEscaper.Replace(fieldKey))
+	b.WriteByte('=')
+	b.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+
+	if t, found := record.Time(m); found {
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(i.unix(t), 10))
+	}
+	b.WriteByte('\n')
+
+	_, err = i.buf.Write(b.Bytes())
+	return err
+}
+
+func (i *Influx) unix(t time.Time) int64 {
+	switch i.config.Precision {
+	case PrecisionSeconds:
+		return t.Unix()
+	case PrecisionMilliseconds:
+		return t.UnixMilli()
+	case PrecisionMicroseconds:
+		return t.UnixMicro()
+	default:
+		return t.UnixNano()
+	}
+}
+
+func toFloat(v any) (float64, error) {
+	switch tv := v.(type) {
+	case float64:
+		return tv, nil
+	case float32:
+		return float64(tv), nil
+	case int:
+		return float64(tv), nil
+	case int64:
+		return float64(tv), nil
+	case int32:
+		return float64(tv), nil
+	default:
+		return 0, fmt.Errorf("influx encoding unable to convert value: %v to float", v)
+	}
+}
+
+const fieldKey = "value"
+
+var measurementEscaper = strings.NewReplacer(
+	",", `\,`,
+	" ", `\ `,
+)
+
+// tagEscaper escapes tag keys, tag values and field keys.
+var tagEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	",", `\,`,
+	"=", `\=`,
+	" ", `\ `,
+)
+
+func NewFromGenericConfig(m map[string]any) (*Influx, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	switch conf.Precision {
+	case "":
+		conf.Precision = PrecisionNanoseconds
+	case PrecisionNanoseconds, PrecisionMicroseconds, PrecisionMilliseconds, PrecisionSeconds:
+	default:
+		return nil, fmt.Errorf("influx precision: %q not supported", conf.Precision)
+	}
+
+	return &Influx{
+		config: conf,
+	}, nil
+}
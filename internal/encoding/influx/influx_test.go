@@ -0,0 +1,117 @@
+package influx
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"testing"
+	"time"
+)
+
+func TestInflux_Write(t *testing.T) {
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		precision string
+		metric    *metric.Metric
+		expected  string
+	}{
+		{
+			name: "window_timestamp",
+			metric: &metric.Metric{
+				Name:      "core.users.total",
+				Value:     3,
+				Timestamp: ts,
+				Window:    &window,
+				Tags: map[string]string{
+					"env":      "prod",
+					"customer": "google",
+				},
+			},
+			expected: "core.users.total,customer=google,env=prod value=3 1704067200000000000\n",
+		},
+		{
+			name:      "tick_timestamp_seconds",
+			precision: "s",
+			metric: &metric.Metric{
+				Name:      "core.users.total",
+				Value:     1.5,
+				Timestamp: ts,
+				Tags:      map[string]string{},
+			},
+			expected: "core.users.total value=1.5 1704070800\n",
+		},
+		{
+			name: "escapes",
+			metric: &metric.Metric{
+				Name:      "core users,total",
+				Value:     1,
+				Timestamp: ts,
+				Tags: map[string]string{
+					"a b": "c=d,e",
+				},
+			},
+			expected: `core\ users\,total,a\ b=c\=d\,e value=1 1704070800000000000` + "\n",
+		},
+		{
+			name: "escapes_backslash",
+			metric: &metric.Metric{
+				Name:      "core.users.total",
+				Value:     1,
+				Timestamp: ts,
+				Tags: map[string]string{
+					`a\b`: `c\`,
+				},
+			},
+			expected: `core.users.total,a\\b=c\\ value=1 1704070800000000000` + "\n",
+		},
+		{
+			name: "drops_empty_tag_values",
+			metric: &metric.Metric{
+				Name:      "core.users.total",
+				Value:     1,
+				Timestamp: ts,
+				Tags: map[string]string{
+					"env":      "",
+					"customer": "google",
+				},
+			},
+			expected: "core.users.total,customer=google value=1 1704070800000000000\n",
+		},
+		{
+			name: "drops_all_empty_tag_values",
+			metric: &metric.Metric{
+				Name:      "core.users.total",
+				Value:     1,
+				Timestamp: ts,
+				Tags: map[string]string{
+					"env": "",
+				},
+			},
+			expected: "core.users.total value=1 1704070800000000000\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewFromGenericConfig(map[string]any{
+				"precision": tc.precision,
+			})
+			assert.NoError(t, err)
+
+			buf := &bytes.Buffer{}
+			assert.NoError(t, e.Init(buf))
+			assert.NoError(t, e.Write(tc.metric.Map()))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestNewFromGenericConfig_InvalidPrecision(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"precision": "h",
+	})
+	assert.EqualError(t, err, "influx precision: \"h\" not supported")
+}
@@ -0,0 +1,55 @@
+package invoker
+
+import (
+	"fmt"
+	"time"
+)
+
+type DeadLetterPolicy string
+
+const (
+	// DeadLetterPolicyFail fails the invocation after the undelivered
+	// records have been dead-lettered, so the window is retried.
+	DeadLetterPolicyFail DeadLetterPolicy = "fail"
+	// DeadLetterPolicyContinue completes the invocation once the
+	// undelivered records have been dead-lettered.
+	DeadLetterPolicyContinue DeadLetterPolicy = "continue"
+)
+
+func (p DeadLetterPolicy) Validate() error {
+	switch p {
+	case DeadLetterPolicyFail, DeadLetterPolicyContinue:
+		return nil
+	default:
+		return fmt.Errorf("unknown dead letter policy: %q", p)
+	}
+}
+
+// DeadLetter receives the records a collector could not deliver to
+// one of its sinks.
+type DeadLetter struct {
+	Sinker Sinker
+	Policy DeadLetterPolicy
+}
+
+// DeadLetterRecord wraps an undelivered record with the reason it was
+// not delivered.
+type DeadLetterRecord struct {
+	Collector    string
+	InvocationID string
+	Sink         string
+	Err          error
+	Record       map[string]any
+	Time         time.Time
+}
+
+func (d DeadLetterRecord) Map() map[string]any {
+	return map[string]any{
+		"collector":     d.Collector,
+		"invocation_id": d.InvocationID,
+		"sink":          d.Sink,
+		"error":         d.Err.Error(),
+		"record":        d.Record,
+		"timestamp":     d.Time,
+	}
+}
@@ -2,10 +2,13 @@ package invoker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/turbolytics/latte/internal/breaker"
 	"github.com/turbolytics/latte/internal/obs"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 	"github.com/turbolytics/latte/internal/source"
 	"github.com/turbolytics/latte/internal/timeseries"
@@ -14,6 +17,7 @@ import (
 	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 	"io"
+	"sync"
 	"time"
 )
 
@@ -55,9 +59,34 @@ type Sinker interface {
 	Flush(context.Context) error
 }
 
+// Sink is a named sinker of a collector. Failures of sinks which are
+// not required are logged without failing the invocation.
+type Sink struct {
+	Name     string
+	Required bool
+	Retry    *retry.Policy
+	Breaker  *breaker.Breaker
+	Sinker
+}
+
+// Pinger is implemented by sources and sinks which are able to check
+// their connectivity.
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+// Ping checks the connectivity of v, if it supports it.
+func Ping(ctx context.Context, v any) error {
+	if p, ok := v.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 type Schedule interface {
 	Interval() *time.Duration
 	Cron() *string
+	Splay() time.Duration
 }
 
 type Storer interface {
@@ -74,9 +103,17 @@ type Transformer interface {
 type Collector interface {
 	Name() string
 	InvocationStrategy() TypeStrategy
-	Sinks() []Sinker
+	Sinks() []Sink
+	DeadLetter() *DeadLetter
+	Retry() *retry.Policy
+	Breaker() *breaker.Breaker
+	// Timeout bounds each invocation, zero means no timeout.
+	Timeout() time.Duration
 	Schedule() Schedule
 	Sourcer() Sourcer
+	// SourceGroup is the concurrency group of the source, empty when
+	// the source is not part of a group.
+	SourceGroup() string
 	Storer() Storer
 	Transformer() Transformer
 }
@@ -100,16 +137,59 @@ func WithStartTime(t time.Time) Option {
 type Invoker struct {
 	Collector Collector
 
+	// deadLetterMu serializes dead letter writes of concurrent sinks.
+	deadLetterMu sync.Mutex
+
 	logger *zap.Logger
 	now    func() time.Time
 }
 
+// Close closes every sink, the dead letter sink and the state store,
+// returning all errors encountered.
 func (i *Invoker) Close() error {
+	var errs []error
 	ss := i.Collector.Sinks()
 	for _, s := range ss {
-		s.Close()
+		if err := s.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sink: %q: %w", s.Name, err))
+		}
+		if err := s.Breaker.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("sink: %q breaker: %w", s.Name, err))
+		}
 	}
-	return nil
+	if err := i.Collector.Breaker().Close(); err != nil {
+		errs = append(errs, fmt.Errorf("breaker: %w", err))
+	}
+	if dl := i.Collector.DeadLetter(); dl != nil {
+		if err := dl.Sinker.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("dead letter: %w", err))
+		}
+	}
+	if st := i.Collector.Storer(); st != nil {
+		if err := st.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("state store: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Ping checks the connectivity of the source and every sink.
+func (i *Invoker) Ping(ctx context.Context) error {
+	var errs []error
+	if err := Ping(ctx, i.Collector.Sourcer()); err != nil {
+		errs = append(errs, fmt.Errorf("source: %w", err))
+	}
+	for _, s := range i.Collector.Sinks() {
+		if err := Ping(ctx, s.Sinker); err != nil {
+			errs = append(errs, fmt.Errorf("sink: %q: %w", s.Name, err))
+		}
+	}
+	if dl := i.Collector.DeadLetter(); dl != nil {
+		if err := Ping(ctx, dl.Sinker); err != nil {
+			errs = append(errs, fmt.Errorf("dead letter: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // InvokeHandleError will log any Invoke errors and not return them.
@@ -130,6 +210,11 @@ func (i *Invoker) Source(ctx context.Context) (sr record.Result, err error) {
 	)
 
 	s := i.Collector.Sourcer()
+	b := i.Collector.Breaker()
+
+	if err := b.Allow(); err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
 
 	defer func() {
 		duration := time.Since(start)
@@ -154,7 +239,13 @@ func (i *Invoker) Source(ctx context.Context) (sr record.Result, err error) {
 		)
 	}()
 
-	sr, err = s.Source(ctx)
+	attempts, err := i.Collector.Retry().Do(ctx, func(ctx context.Context) error {
+		var err error
+		sr, err = s.Source(ctx)
+		return err
+	})
+	i.recordAttempts(ctx, "source", string(s.Type()), attempts, err)
+	b.Record(err)
 	if err != nil {
 		return nil, err
 	}
@@ -296,35 +387,79 @@ func (i *Invoker) invokeHistoricTumblingWindow(ctx context.Context) error {
 		)
 		return fmt.Errorf("backfilling multiple windows not yet supported: %v", windows)
 	}
-
-	return nil
 }
 
+// Sink writes and flushes the records to all sinks concurrently. With
+// a dead letter, the records a sink could not deliver are dead-lettered
+// and the dead letter policy decides if failures of required sinks
+// fail the invocation.
 func (i *Invoker) Sink(ctx context.Context, res record.Result) error {
-	histogram, _ := meter.Float64Histogram(
-		"collector.sink.duration",
-		metric.WithUnit("s"),
-	)
 	// add tags from config
 	rs := res.Records()
 
 	sinks := i.Collector.Sinks()
+	dl := i.Collector.DeadLetter()
+
+	sinkErrs := make([]error, len(sinks))
+	var wg sync.WaitGroup
 
 	// need to add a serializer
-	for _, s := range sinks {
-		start := time.Now().UTC()
-		var err error
+	for idx, s := range sinks {
+		wg.Add(1)
+		go func(idx int, s Sink) {
+			defer wg.Done()
+			sinkErrs[idx] = i.sink(ctx, s, rs, dl)
+		}(idx, s)
+	}
+	wg.Wait()
 
-		for _, r := range rs {
+	var errs []error
+	for idx, err := range sinkErrs {
+		if err == nil {
+			continue
+		}
+		s := sinks[idx]
+		err = fmt.Errorf("sink: %q: %w", s.Name, err)
+		if !s.Required {
+			i.logger.Warn(
+				"invoker.Sink",
+				zap.String("msg", "optional sink failed"),
+				zap.String("name", i.Collector.Name()),
+				zap.String("sink", s.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+		errs = append(errs, err)
+	}
 
-			_, err = s.Write(ctx, r)
+	if dl != nil {
+		if err := dl.Sinker.Flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("dead letter: %w", err))
+		}
+	}
 
-			if err != nil {
-				break
-			}
+	err := errors.Join(errs...)
+	if err != nil && dl != nil && dl.Policy == DeadLetterPolicyContinue {
+		i.logger.Error(
+			"invoker.Sink",
+			zap.String("msg", "continuing after sink errors"),
+			zap.String("name", i.Collector.Name()),
+			zap.Error(err),
+		)
+		return nil
+	}
+	return err
+}
 
-		}
+func (i *Invoker) sink(ctx context.Context, s Sink, rs []record.Record, dl *DeadLetter) (err error) {
+	histogram, _ := meter.Float64Histogram(
+		"collector.sink.duration",
+		metric.WithUnit("s"),
+	)
 
+	start := time.Now().UTC()
+	defer func() {
 		duration := time.Since(start)
 		histogram.Record(ctx, duration.Seconds(), metric.WithAttributeSet(
 			attribute.NewSet(
@@ -332,16 +467,165 @@ func (i *Invoker) Sink(ctx context.Context, res record.Result) error {
 				attribute.String("sink.name", string(s.Type())),
 			),
 		))
+	}()
 
-		if err != nil {
+	// an open breaker skips the sink, the records are diverted to the
+	// dead letter when configured.
+	if err := s.Breaker.Allow(); err != nil {
+		if dl == nil {
 			return err
 		}
+		failed := make([]failure, 0, len(rs))
+		for _, r := range rs {
+			failed = append(failed, failure{record: r, err: err})
+		}
+		if dlErr := i.deadLetter(ctx, dl, s.Name, failed); dlErr != nil {
+			return errors.Join(err, fmt.Errorf("dead letter: %w", dlErr))
+		}
+		return err
+	}
 
-		if err := s.Flush(ctx); err != nil {
-			return err
+	pending := rs
+	var permanent, retryable []failure
+
+	attempts, err := s.Retry.Do(ctx, func(ctx context.Context) error {
+		// only the undelivered records are retried, records which
+		// failed permanently are not written again.
+		retryable = retryable[:0:0]
+		for _, f := range i.deliver(ctx, s, pending) {
+			if retry.IsPermanent(f.err) {
+				permanent = append(permanent, f)
+				continue
+			}
+			retryable = append(retryable, f)
+		}
+		pending = pending[:0:0]
+		for _, f := range retryable {
+			pending = append(pending, f.record)
+		}
+
+		undelivered := len(permanent) + len(retryable)
+		switch {
+		case undelivered == 0:
+			return nil
+		case len(retryable) == 0:
+			return retry.Permanent(fmt.Errorf("%d of %d records not delivered: %w", undelivered, len(rs), permanent[0].err))
+		default:
+			return fmt.Errorf("%d of %d records not delivered: %w", undelivered, len(rs), retryable[0].err)
+		}
+	})
+	i.recordAttempts(ctx, "sink", s.Name, attempts, err)
+	s.Breaker.Record(err)
+
+	failed := append(permanent, retryable...)
+
+	if err != nil && dl != nil {
+		if dlErr := i.deadLetter(ctx, dl, s.Name, failed); dlErr != nil {
+			err = errors.Join(err, fmt.Errorf("dead letter: %w", dlErr))
 		}
 	}
-	return nil
+
+	return err
+}
+
+type failure struct {
+	record record.Record
+	err    error
+}
+
+// deliver writes and flushes the records, returning the records which
+// were not delivered.
+func (i *Invoker) deliver(ctx context.Context, s Sink, rs []record.Record) []failure {
+	var failed []failure
+	var written []record.Record
+
+	for _, r := range rs {
+		if _, err := s.Write(ctx, r); err != nil {
+			failed = append(failed, failure{record: r, err: err})
+			continue
+		}
+		written = append(written, r)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		// buffered records are not known to the invoker, so every
+		// record written since the last flush is undelivered.
+		for _, r := range written {
+			failed = append(failed, failure{record: r, err: err})
+		}
+	}
+
+	return failed
+}
+
+func (i *Invoker) recordAttempts(ctx context.Context, stage string, target string, attempts int, err error) {
+	counter, _ := meter.Int64Counter(
+		"collector.retry.attempts",
+	)
+
+	counter.Add(ctx, int64(attempts), metric.WithAttributeSet(
+		attribute.NewSet(
+			attribute.String("result.status_code", obs.ErrToStatus(err)),
+			attribute.String("collector.name", i.Collector.Name()),
+			attribute.String("stage", stage),
+			attribute.String("target", target),
+		),
+	))
+
+	if attempts > 1 {
+		i.logger.Warn(
+			"invoker.retry",
+			zap.String("name", i.Collector.Name()),
+			zap.String("stage", stage),
+			zap.String("target", target),
+			zap.Int("attempts", attempts),
+			zap.Error(err),
+		)
+	}
+}
+
+func (i *Invoker) deadLetter(ctx context.Context, dl *DeadLetter, sinkName string, failed []failure) error {
+	counter, _ := meter.Int64Counter(
+		"collector.sink.dead_letter.count",
+	)
+
+	id, _ := ctx.Value("id").(uuid.UUID)
+	now := i.now()
+
+	i.deadLetterMu.Lock()
+	defer i.deadLetterMu.Unlock()
+
+	var err error
+	for _, f := range failed {
+		_, err = dl.Sinker.Write(ctx, DeadLetterRecord{
+			Collector:    i.Collector.Name(),
+			InvocationID: id.String(),
+			Sink:         sinkName,
+			Err:          f.err,
+			Record:       f.record.Map(),
+			Time:         now,
+		})
+		if err != nil {
+			break
+		}
+	}
+
+	counter.Add(ctx, int64(len(failed)), metric.WithAttributeSet(
+		attribute.NewSet(
+			attribute.String("collector.name", i.Collector.Name()),
+			attribute.String("sink.name", sinkName),
+		),
+	))
+
+	i.logger.Warn(
+		"invoker.deadLetter",
+		zap.String("id", id.String()),
+		zap.String("name", i.Collector.Name()),
+		zap.String("sink", sinkName),
+		zap.Int("records", len(failed)),
+	)
+
+	return err
 }
 
 func (i *Invoker) Invoke(ctx context.Context) (err error) {
@@ -383,18 +667,30 @@ func (i *Invoker) Invoke(ctx context.Context) (err error) {
 	)
 	ctx = context.WithValue(ctx, "id", id)
 	ctx = context.WithValue(ctx, "invocation.start", start)
+	ctx = context.WithValue(ctx, "collector.name", i.Collector.Name())
+
+	// the timeout bounds every source and sink call of the invocation
+	timeout := i.Collector.Timeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	strat := i.Collector.InvocationStrategy()
 	switch strat {
 	case TypeStrategyHistoricTumblingWindow:
-		return i.invokeHistoricTumblingWindow(ctx)
+		err = i.invokeHistoricTumblingWindow(ctx)
 	case TypeStrategyTick:
-		return i.invokeTick(ctx)
+		err = i.invokeTick(ctx)
 	default:
 		return fmt.Errorf("strategy: %q not supported", strat)
 	}
 
-	return nil
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("invocation exceeded timeout: %s: %w", timeout, err)
+	}
+	return err
 }
 
 func New(collector Collector, opts ...Option) (*Invoker, error) {
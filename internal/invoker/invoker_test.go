@@ -2,8 +2,12 @@ package invoker
 
 import (
 	"context"
+	"errors"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/breaker"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"go.uber.org/zap"
 	"testing"
 	"time"
@@ -34,6 +38,25 @@ func TestCollector_Close(t *testing.T) {
 	assert.Equal(t, 1, sink.closes)
 }
 
+func TestCollector_Close_ClosesAll(t *testing.T) {
+	failing := &TestSink{closeErr: errors.New("broken pipe")}
+	ok := &TestSink{}
+	dl := &TestSink{}
+	i := &Invoker{
+		logger: zap.NewNop(),
+		Collector: TestConfig{
+			sinks: []*TestSink{failing, ok},
+			deadLetter: &DeadLetter{
+				Sinker: dl,
+			},
+		},
+	}
+	err := i.Close()
+	assert.EqualError(t, err, "sink: \"tester_0\": broken pipe")
+	assert.Equal(t, 1, ok.closes)
+	assert.Equal(t, 1, dl.closes)
+}
+
 func TestInvoker_Invoke_Tick_Success(t *testing.T) {
 	sink := &TestSink{}
 	i := &Invoker{
@@ -302,3 +325,319 @@ func TestCollector_invokeWindow_PreviousInvocations_SingleWindowPassed(t *testin
 	}, i)
 }
 */
+
+func TestInvoker_Sink_DeadLetter(t *testing.T) {
+	testCases := []struct {
+		name   string
+		policy DeadLetterPolicy
+		err    string
+	}{
+		{
+			name:   "fail",
+			policy: DeadLetterPolicyFail,
+			err:    "sink: \"tester_0\": 2 of 2 records not delivered: write failed",
+		},
+		{
+			name:   "continue",
+			policy: DeadLetterPolicyContinue,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failing := &TestSink{writeErr: errors.New("write failed")}
+			healthy := &TestSink{}
+			dl := &TestSink{}
+
+			i := &Invoker{
+				logger: zap.NewNop(),
+				now: func() time.Time {
+					return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+				},
+				Collector: TestConfig{
+					name:  "test_collector",
+					sinks: []*TestSink{failing, healthy},
+					deadLetter: &DeadLetter{
+						Sinker: dl,
+						Policy: tc.policy,
+					},
+				},
+			}
+
+			id := uuid.New()
+			ctx := context.WithValue(context.Background(), "id", id)
+			err := i.Sink(ctx, TestResult{
+				records: []*TestRecord{
+					{m: map[string]any{"key": "1"}},
+					{m: map[string]any{"key": "2"}},
+				},
+			})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			// the remaining sinks still receive every record
+			assert.Equal(t, 2, len(healthy.writes))
+
+			assert.Equal(t, 1, dl.flushes)
+			assert.Equal(t, 2, len(dl.writes))
+			assert.Equal(t, map[string]any{
+				"collector":     "test_collector",
+				"invocation_id": id.String(),
+				"sink":          "tester_0",
+				"error":         "write failed",
+				"record":        map[string]any{"key": "1"},
+				"timestamp":     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			}, dl.writes[0].Map())
+		})
+	}
+}
+
+func TestInvoker_Sink_DeadLetter_FlushError(t *testing.T) {
+	failing := &TestSink{flushErr: errors.New("flush failed")}
+	dl := &TestSink{}
+
+	i := &Invoker{
+		logger: zap.NewNop(),
+		now:    time.Now,
+		Collector: TestConfig{
+			sinks: []*TestSink{failing},
+			deadLetter: &DeadLetter{
+				Sinker: dl,
+				Policy: DeadLetterPolicyContinue,
+			},
+		},
+	}
+
+	err := i.Sink(context.Background(), TestResult{
+		records: []*TestRecord{
+			{m: map[string]any{"key": "1"}},
+			{m: map[string]any{"key": "2"}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(dl.writes))
+}
+
+func TestInvoker_Sink_Required(t *testing.T) {
+	testCases := []struct {
+		name     string
+		optional bool
+		err      string
+	}{
+		{
+			name: "required_sink_fails_invocation",
+			err:  "sink: \"tester_0\": 1 of 1 records not delivered: write failed",
+		},
+		{
+			name:     "optional_sink_is_logged",
+			optional: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failing := &TestSink{
+				optional: tc.optional,
+				writeErr: errors.New("write failed"),
+			}
+			healthy := &TestSink{}
+
+			i := &Invoker{
+				logger: zap.NewNop(),
+				now:    time.Now,
+				Collector: TestConfig{
+					sinks: []*TestSink{failing, healthy},
+				},
+			}
+
+			err := i.Sink(context.Background(), TestResult{
+				records: []*TestRecord{
+					{m: map[string]any{"key": "1"}},
+				},
+			})
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, 1, len(healthy.writes))
+			assert.Equal(t, 1, healthy.flushes)
+		})
+	}
+}
+
+type flakySourcer struct {
+	TestSourcer
+	failures int
+	calls    *int
+}
+
+func (fs flakySourcer) Source(ctx context.Context) (record.Result, error) {
+	*fs.calls++
+	if *fs.calls <= fs.failures {
+		return nil, errors.New("connection reset")
+	}
+	return fs.TestSourcer.Source(ctx)
+}
+
+type retryConfig struct {
+	TestConfig
+	sourcer Sourcer
+}
+
+func (r retryConfig) Sourcer() Sourcer {
+	return r.sourcer
+}
+
+func TestInvoker_Invoke_Tick_Retry(t *testing.T) {
+	noSleep := retry.WithSleep(func(context.Context, time.Duration) error {
+		return nil
+	})
+
+	var calls int
+	flaky := &TestSink{flushErr: errors.New("unavailable")}
+	i := &Invoker{
+		logger: zap.NewNop(),
+		now:    time.Now,
+		Collector: retryConfig{
+			TestConfig: TestConfig{
+				invocationStrategy: TypeStrategyTick,
+				sinks:              []*TestSink{flaky},
+				retry:              retry.New(retry.Config{MaxAttempts: 3}, noSleep),
+			},
+			sourcer: flakySourcer{
+				TestSourcer: TestSourcer{
+					tr: TestResult{
+						records: []*TestRecord{
+							{m: map[string]any{"key": "value"}},
+						},
+					},
+				},
+				failures: 2,
+				calls:    &calls,
+			},
+		},
+	}
+
+	err := i.Invoke(context.Background())
+	assert.EqualError(t, err, "sink: \"tester_0\": 1 of 1 records not delivered: unavailable")
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 3, flaky.flushes)
+	// the record is written again on each attempt
+	assert.Equal(t, 3, len(flaky.writes))
+}
+
+// partialSink fails the `bad` record permanently and the `flaky`
+// record on its first write.
+type partialSink struct {
+	TestSink
+	attempts map[string]int
+}
+
+func (p *partialSink) Write(ctx context.Context, r record.Record) (int, error) {
+	key := r.Map()["key"].(string)
+	p.attempts[key]++
+	switch {
+	case key == "bad":
+		return 0, retry.Permanent(errors.New("unsupported record"))
+	case key == "flaky" && p.attempts[key] == 1:
+		return 0, errors.New("unavailable")
+	}
+	return 0, nil
+}
+
+func TestInvoker_sink_PermanentNotRetried(t *testing.T) {
+	noSleep := retry.WithSleep(func(context.Context, time.Duration) error {
+		return nil
+	})
+
+	ps := &partialSink{attempts: make(map[string]int)}
+	dl := &TestSink{}
+	i := &Invoker{
+		logger:    zap.NewNop(),
+		now:       time.Now,
+		Collector: TestConfig{name: "test_collector"},
+	}
+
+	err := i.sink(context.Background(), Sink{
+		Name:   "partial",
+		Retry:  retry.New(retry.Config{MaxAttempts: 3}, noSleep),
+		Sinker: ps,
+	}, []record.Record{
+		&TestRecord{m: map[string]any{"key": "bad"}},
+		&TestRecord{m: map[string]any{"key": "flaky"}},
+	}, &DeadLetter{Sinker: dl})
+
+	assert.EqualError(t, err, "1 of 2 records not delivered: unsupported record")
+	assert.True(t, retry.IsPermanent(err))
+	assert.Equal(t, map[string]int{"bad": 1, "flaky": 2}, ps.attempts)
+	assert.Equal(t, 1, len(dl.writes))
+	assert.Equal(t, map[string]any{"key": "bad"}, dl.writes[0].Map()["record"])
+}
+
+func TestInvoker_Sink_BreakerOpen_DeadLetters(t *testing.T) {
+	b, err := breaker.New(breaker.Config{Failures: 1, Cooldown: time.Hour}, nil)
+	assert.NoError(t, err)
+
+	failing := &TestSink{writeErr: errors.New("connection refused")}
+	dl := &TestSink{}
+
+	i := &Invoker{
+		logger: zap.NewNop(),
+		now:    time.Now,
+		Collector: TestConfig{
+			sinks:        []*TestSink{failing},
+			sinkBreakers: []*breaker.Breaker{b},
+			deadLetter: &DeadLetter{
+				Sinker: dl,
+				Policy: DeadLetterPolicyContinue,
+			},
+		},
+	}
+
+	res := TestResult{
+		records: []*TestRecord{
+			{m: map[string]any{"key": "1"}},
+		},
+	}
+
+	assert.NoError(t, i.Sink(context.Background(), res))
+	assert.Equal(t, breaker.StateOpen, b.State())
+	assert.Equal(t, 1, failing.flushes)
+
+	// while open the sink is skipped and the records are dead-lettered
+	assert.NoError(t, i.Sink(context.Background(), res))
+	assert.Equal(t, 1, failing.flushes)
+	assert.Equal(t, 2, len(dl.writes))
+	assert.Equal(t, "circuit breaker open", dl.writes[1].Map()["error"])
+}
+
+type blockingSourcer struct {
+	TestSourcer
+}
+
+func (blockingSourcer) Source(ctx context.Context) (record.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestInvoker_Invoke_Timeout(t *testing.T) {
+	i := &Invoker{
+		logger: zap.NewNop(),
+		now:    time.Now,
+		Collector: retryConfig{
+			TestConfig: TestConfig{
+				invocationStrategy: TypeStrategyTick,
+				timeout:            10 * time.Millisecond,
+			},
+			sourcer: blockingSourcer{},
+		},
+	}
+
+	err := i.Invoke(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "invocation exceeded timeout: 10ms")
+}
@@ -2,7 +2,10 @@ package invoker
 
 import (
 	"context"
+	"fmt"
+	"github.com/turbolytics/latte/internal/breaker"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 	"github.com/turbolytics/latte/internal/source"
 	"time"
@@ -45,8 +48,13 @@ func (tr TestResult) Records() []record.Record {
 }
 
 type TestSink struct {
-	closes int
-	writes []record.Record
+	optional bool
+	closes   int
+	flushes  int
+	writes   []record.Record
+	writeErr error
+	flushErr error
+	closeErr error
 }
 
 func (ts TestSink) Type() sink.Type {
@@ -54,17 +62,21 @@ func (ts TestSink) Type() sink.Type {
 }
 
 func (ts *TestSink) Write(ctx context.Context, r record.Record) (int, error) {
+	if ts.writeErr != nil {
+		return 0, ts.writeErr
+	}
 	ts.writes = append(ts.writes, r)
 	return 0, nil
 }
 
 func (ts *TestSink) Flush(ctx context.Context) error {
-	return nil
+	ts.flushes++
+	return ts.flushErr
 }
 
 func (ts *TestSink) Close() error {
 	ts.closes++
-	return nil
+	return ts.closeErr
 }
 
 type TestSourcer struct {
@@ -90,6 +102,11 @@ type TestConfig struct {
 	name               string
 	sourcer            TestSourcer
 	sinks              []*TestSink
+	deadLetter         *DeadLetter
+	retry              *retry.Policy
+	breaker            *breaker.Breaker
+	sinkBreakers       []*breaker.Breaker
+	timeout            time.Duration
 	transformer        TestTransformer
 }
 
@@ -105,14 +122,40 @@ func (t TestConfig) Name() string {
 	return t.name
 }
 
-func (t TestConfig) Sinks() []Sinker {
-	var sinks []Sinker
-	for _, sink := range t.sinks {
-		sinks = append(sinks, sink)
+func (t TestConfig) Sinks() []Sink {
+	var sinks []Sink
+	for i, sink := range t.sinks {
+		var b *breaker.Breaker
+		if i < len(t.sinkBreakers) {
+			b = t.sinkBreakers[i]
+		}
+		sinks = append(sinks, Sink{
+			Breaker:  b,
+			Name:     fmt.Sprintf("tester_%d", i),
+			Required: !sink.optional,
+			Retry:    t.retry,
+			Sinker:   sink,
+		})
 	}
 	return sinks
 }
 
+func (t TestConfig) DeadLetter() *DeadLetter {
+	return t.deadLetter
+}
+
+func (t TestConfig) Retry() *retry.Policy {
+	return t.retry
+}
+
+func (t TestConfig) Breaker() *breaker.Breaker {
+	return t.breaker
+}
+
+func (t TestConfig) Timeout() time.Duration {
+	return t.timeout
+}
+
 func (t TestConfig) Schedule() Schedule {
 	return nil
 }
@@ -121,6 +164,10 @@ func (t TestConfig) Sourcer() Sourcer {
 	return t.sourcer
 }
 
+func (t TestConfig) SourceGroup() string {
+	return ""
+}
+
 func (t TestConfig) Storer() Storer {
 	return nil
 }
@@ -3,6 +3,7 @@ package metric
 import (
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/turbolytics/latte/internal/record"
 	"strconv"
 	"time"
 )
@@ -40,7 +41,7 @@ func (m *Metric) Map() map[string]any {
 	}
 
 	for k, v := range m.Tags {
-		tagK := fmt.Sprintf("tag.%s", k)
+		tagK := record.TagPrefix + k
 		fmt.Println(k, v)
 		s[tagK] = v
 	}
@@ -62,13 +62,19 @@ func NewMeterProvider(exporter Exporter, res *resource.Resource) (*metric.MeterP
 	return meterProvider, nil
 }
 
-func ServeMetrics(l *zap.Logger, addr string) {
+// HandleMetrics serves latte's own telemetry at /metrics.
+func HandleMetrics() {
+	http.Handle("/metrics", promhttp.Handler())
+}
+
+// Serve starts the daemon http server on the default mux, which
+// serves telemetry and any paths registered by sinks.
+func Serve(l *zap.Logger, addr string) {
 	l.Info(
-		"serving metrics at /metrics",
+		"serving http",
 		zap.String("addr", addr),
 	)
 
-	http.Handle("/metrics", promhttp.Handler())
 	err := http.ListenAndServe(addr, nil) //nolint:gosec // Ignoring G114: Use of net/http serve function that has no support for setting timeouts.
 	if err != nil {
 		fmt.Printf("error serving http: %v", err)
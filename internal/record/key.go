@@ -0,0 +1,67 @@
+package record
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TagPrefix prefixes the tag keys of a record map, ie: `tag.customer`.
+const TagPrefix = "tag."
+
+// Time returns the window of a record map, falling back to its
+// timestamp for records collected without a window.
+func Time(m map[string]any) (time.Time, bool) {
+	for _, k := range []string{"window", "timestamp"} {
+		switch v := m[k].(type) {
+		case *time.Time:
+			if v != nil {
+				return *v, true
+			}
+		case time.Time:
+			if !v.IsZero() {
+				return v, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Tags returns the `tag.*` values of a record map keyed by tag name.
+func Tags(m map[string]any) map[string]string {
+	tags := make(map[string]string)
+	for k, v := range m {
+		if strings.HasPrefix(k, TagPrefix) {
+			tags[strings.TrimPrefix(k, TagPrefix)] = fmt.Sprint(v)
+		}
+	}
+	return tags
+}
+
+// Key derives a stable identifier from the name, time and tags of a
+// record map. Re-collecting the same window produces the same key,
+// which sinks use to write idempotently.
+func Key(m map[string]any) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v\x00", m["name"])
+
+	if t, ok := Time(m); ok {
+		fmt.Fprintf(h, "%s\x00", t.UTC().Format(time.RFC3339Nano))
+	}
+
+	tags := Tags(m)
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\x00", k, tags[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
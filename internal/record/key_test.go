@@ -0,0 +1,42 @@
+package record
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	base := map[string]any{
+		"uuid":         "1",
+		"name":         "core.users.total",
+		"value":        1.0,
+		"window":       &window,
+		"tag.customer": "google",
+	}
+
+	// uuid and value do not change the key
+	same := map[string]any{
+		"uuid":         "2",
+		"name":         "core.users.total",
+		"value":        2.0,
+		"window":       &window,
+		"tag.customer": "google",
+	}
+	assert.Equal(t, Key(base), Key(same))
+
+	otherTag := map[string]any{
+		"name":         "core.users.total",
+		"window":       &window,
+		"tag.customer": "amazon",
+	}
+	assert.NotEqual(t, Key(base), Key(otherTag))
+
+	otherWindow := map[string]any{
+		"name":         "core.users.total",
+		"window":       window.Add(time.Hour),
+		"tag.customer": "google",
+	}
+	assert.NotEqual(t, Key(base), Key(otherWindow))
+}
@@ -0,0 +1,192 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 30 * time.Second
+	defaultMultiplier     = 2
+	defaultJitter         = 0.2
+)
+
+type Config struct {
+	MaxAttempts    int           `yaml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	Multiplier     float64
+	// Jitter randomizes each backoff by up to this fraction of it,
+	// defaults to 0.2 when unset. 0 disables it.
+	Jitter *float64
+	// NonRetryable errors are not retried when their message contains
+	// any of these substrings.
+	NonRetryable []string `yaml:"non_retryable"`
+}
+
+func (c Config) Validate() error {
+	if c.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts: %d must be positive", c.MaxAttempts)
+	}
+
+	if c.Multiplier != 0 && c.Multiplier < 1 {
+		return fmt.Errorf("retry multiplier: %v must be at least 1", c.Multiplier)
+	}
+
+	if c.Jitter != nil && (*c.Jitter < 0 || *c.Jitter > 1) {
+		return fmt.Errorf("retry jitter: %v must be between 0 and 1", *c.Jitter)
+	}
+
+	if c.MaxBackoff != 0 && c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("retry max_backoff: %s is less than initial_backoff: %s", c.MaxBackoff, c.InitialBackoff)
+	}
+
+	return nil
+}
+
+func (c *Config) SetDefaults() {
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = defaultMaxAttempts
+	}
+	if c.InitialBackoff == 0 {
+		c.InitialBackoff = defaultInitialBackoff
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	if c.Multiplier == 0 {
+		c.Multiplier = defaultMultiplier
+	}
+	if c.Jitter == nil {
+		jitter := defaultJitter
+		c.Jitter = &jitter
+	}
+}
+
+// PermanentError is never retried.
+type PermanentError struct {
+	Err error
+}
+
+func (p *PermanentError) Error() string {
+	return p.Err.Error()
+}
+
+func (p *PermanentError) Unwrap() error {
+	return p.Err
+}
+
+// Permanent marks err as not retryable.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsPermanent reports if err, or an error it wraps, was marked as not
+// retryable.
+func IsPermanent(err error) bool {
+	var perm *PermanentError
+	return errors.As(err, &perm)
+}
+
+type Option func(*Policy)
+
+// WithSleep replaces waiting between attempts, used by tests.
+func WithSleep(fn func(context.Context, time.Duration) error) Option {
+	return func(p *Policy) {
+		p.sleep = fn
+	}
+}
+
+// Policy retries an operation with exponential backoff and jitter. A
+// nil policy makes a single attempt.
+type Policy struct {
+	config Config
+	rand   *rand.Rand
+	sleep  func(context.Context, time.Duration) error
+}
+
+// Do calls fn until it succeeds, fails with an error which is not
+// retryable or the attempts are exhausted. It returns the number of
+// attempts made and the last error.
+func (p *Policy) Do(ctx context.Context, fn func(context.Context) error) (int, error) {
+	if p == nil {
+		return 1, fn(ctx)
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= p.config.MaxAttempts || !p.Retryable(ctx, err) {
+			return attempt, err
+		}
+
+		if serr := p.sleep(ctx, p.Backoff(attempt)); serr != nil {
+			return attempt, err
+		}
+	}
+}
+
+// Retryable classifies err. Cancellation, permanent errors and errors
+// matching the configured non retryable messages are not retried.
+func (p *Policy) Retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if IsPermanent(err) {
+		return false
+	}
+
+	msg := err.Error()
+	for _, s := range p.config.NonRetryable {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// Backoff returns the wait after the given attempt.
+func (p *Policy) Backoff(attempt int) time.Duration {
+	d := float64(p.config.InitialBackoff) * math.Pow(p.config.Multiplier, float64(attempt-1))
+	d = math.Min(d, float64(p.config.MaxBackoff))
+
+	// spread the wait across [d - jitter*d, d + jitter*d]
+	d += d * *p.config.Jitter * (2*p.rand.Float64() - 1)
+	return time.Duration(d)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func New(c Config, opts ...Option) *Policy {
+	c.SetDefaults()
+
+	p := &Policy{
+		config: c,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		sleep:  sleep,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Do(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   Config
+		errs     []error
+		attempts int
+		err      string
+	}{
+		{
+			name:     "succeeds_after_transient_errors",
+			config:   Config{MaxAttempts: 3},
+			errs:     []error{errors.New("timeout"), errors.New("timeout"), nil},
+			attempts: 3,
+		},
+		{
+			name:     "attempts_exhausted",
+			config:   Config{MaxAttempts: 2},
+			errs:     []error{errors.New("timeout"), errors.New("timeout")},
+			attempts: 2,
+			err:      "timeout",
+		},
+		{
+			name:     "permanent",
+			config:   Config{MaxAttempts: 3},
+			errs:     []error{Permanent(errors.New("bad request"))},
+			attempts: 1,
+			err:      "bad request",
+		},
+		{
+			name:     "non_retryable_message",
+			config:   Config{MaxAttempts: 3, NonRetryable: []string{"syntax error"}},
+			errs:     []error{errors.New(`pq: syntax error at or near "SELEC"`)},
+			attempts: 1,
+			err:      `pq: syntax error at or near "SELEC"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var waits []time.Duration
+			p := New(tc.config, WithSleep(func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}))
+
+			var calls int
+			attempts, err := p.Do(context.Background(), func(ctx context.Context) error {
+				err := tc.errs[calls]
+				calls++
+				return err
+			})
+
+			assert.Equal(t, tc.attempts, attempts)
+			assert.Equal(t, tc.attempts-1, len(waits))
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do_Nil(t *testing.T) {
+	var p *Policy
+	attempts, err := p.Do(context.Background(), func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+	assert.Equal(t, 1, attempts)
+	assert.EqualError(t, err, "failed")
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	jitter := 0.5
+	p := New(Config{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Jitter:         &jitter,
+	})
+
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 5 * time.Second,
+	} {
+		d := p.Backoff(attempt)
+		assert.GreaterOrEqual(t, d, expected/2)
+		assert.LessOrEqual(t, d, expected*3/2)
+	}
+}
+
+func TestPolicy_Backoff_NoJitter(t *testing.T) {
+	jitter := 0.0
+	p := New(Config{
+		InitialBackoff: time.Second,
+		Jitter:         &jitter,
+	})
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, 2*time.Second, p.Backoff(2))
+	}
+}
+
+func TestConfig_SetDefaults_Jitter(t *testing.T) {
+	var c Config
+	c.SetDefaults()
+	assert.Equal(t, defaultJitter, *c.Jitter)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	jitter := 2.0
+	assert.EqualError(t, Config{Jitter: &jitter}.Validate(), "retry jitter: 2 must be between 0 and 1")
+	assert.EqualError(t, Config{Multiplier: 0.5}.Validate(), "retry multiplier: 0.5 must be at least 1")
+}
@@ -8,6 +8,9 @@ import (
 type Config struct {
 	Interval *time.Duration
 	Cron     *string
+	// Splay delays each invocation by a random duration up to splay,
+	// spreading collectors which share a schedule.
+	Splay *time.Duration
 }
 
 func (s Config) Validate() error {
@@ -19,6 +22,10 @@ func (s Config) Validate() error {
 		return fmt.Errorf("must set either invocation.interval or invocation.cron")
 	}
 
+	if s.Splay != nil && *s.Splay < 0 {
+		return fmt.Errorf("schedule splay: %s must be positive", *s.Splay)
+	}
+
 	return nil
 }
 
@@ -34,6 +41,13 @@ func (s Schedule) Cron() *string {
 	return s.config.Cron
 }
 
+func (s Schedule) Splay() time.Duration {
+	if s.config.Splay == nil {
+		return 0
+	}
+	return *s.config.Splay
+}
+
 func New(c Config) Schedule {
 	return Schedule{
 		config: c,
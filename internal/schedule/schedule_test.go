@@ -10,6 +10,7 @@ import (
 func TestSchedule_Validate(t *testing.T) {
 	h := time.Hour
 	c := "* * * * *"
+	negative := -time.Hour
 
 	testCases := []struct {
 		name     string
@@ -29,6 +30,14 @@ func TestSchedule_Validate(t *testing.T) {
 			},
 			err: fmt.Errorf("must set either invocation.interval or invocation.cro"),
 		},
+		{
+			name: "must_have_positive_splay",
+			schedule: Config{
+				Interval: &h,
+				Splay:    &negative,
+			},
+			err: fmt.Errorf("schedule splay: -1h0m0s must be positive"),
+		},
 		{
 			name: "must_have_valid_strategy",
 			schedule: Config{
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const adminPrefix = "/admin/collectors"
+
+// CollectorStatus is the admin view of a scheduled collector.
+type CollectorStatus struct {
+	Name                string     `json:"name"`
+	Schedule            string     `json:"schedule"`
+	Paused              bool       `json:"paused"`
+	Running             bool       `json:"running"`
+	LastRun             *time.Time `json:"last_run,omitempty"`
+	NextRun             *time.Time `json:"next_run,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+func (s *Service) status(c *collector) CollectorStatus {
+	cs := CollectorStatus{
+		Name:                c.name,
+		Paused:              c.paused,
+		Running:             c.active != nil,
+		ConsecutiveFailures: c.consecutiveFailures,
+	}
+
+	if sch := c.invoker.Collector.Schedule(); sch != nil {
+		if sch.Interval() != nil {
+			cs.Schedule = sch.Interval().String()
+		} else if sch.Cron() != nil {
+			cs.Schedule = *sch.Cron()
+		}
+	}
+
+	if !c.lastRun.IsZero() {
+		t := c.lastRun
+		cs.LastRun = &t
+	}
+
+	if c.job != nil && !c.paused {
+		if t, err := c.job.NextRun(); err == nil && !t.IsZero() {
+			cs.NextRun = &t
+		}
+	}
+
+	if c.lastErr != nil {
+		t := c.lastErrAt
+		cs.LastError = c.lastErr.Error()
+		cs.LastErrorAt = &t
+	}
+	return cs
+}
+
+// Collectors returns the status of every collector in config order.
+func (s *Service) Collectors() []CollectorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	css := make([]CollectorStatus, 0, len(s.order))
+	for _, name := range s.order {
+		css = append(css, s.status(s.collectors[name]))
+	}
+	return css
+}
+
+func (s *Service) Collector(name string) (CollectorStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collectors[name]
+	if !ok {
+		return CollectorStatus{}, ErrCollectorNotFound
+	}
+	return s.status(c), nil
+}
+
+// Trigger invokes a collector immediately, even if it is paused.
+func (s *Service) Trigger(name string) error {
+	s.mu.Lock()
+	c, ok := s.collectors[name]
+	s.mu.Unlock()
+	if !ok {
+		return ErrCollectorNotFound
+	}
+
+	i, err := s.start(c, true)
+	if err != nil {
+		return err
+	}
+	go s.invoke(c, i)
+	return nil
+}
+
+// Pause skips the scheduled invocations of a collector until resumed.
+func (s *Service) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+func (s *Service) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Service) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collectors[name]
+	if !ok {
+		return ErrCollectorNotFound
+	}
+	c.paused = paused
+
+	s.logger.Info(
+		"service.setPaused",
+		zap.String("name", name),
+		zap.Bool("paused", paused),
+	)
+	return nil
+}
+
+// HandleAdmin registers the admin API on mux:
+//
+//	GET  /admin/collectors
+//	GET  /admin/collectors/{name}
+//	POST /admin/collectors/{name}/trigger
+//	POST /admin/collectors/{name}/pause
+//	POST /admin/collectors/{name}/resume
+func (s *Service) HandleAdmin(mux *http.ServeMux) {
+	mux.HandleFunc(adminPrefix, s.handleAdmin)
+	mux.HandleFunc(adminPrefix+"/", s.handleAdmin)
+}
+
+func (s *Service) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, adminPrefix), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Collectors())
+		return
+	}
+
+	name, action, _ := strings.Cut(path, "/")
+	if action == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		cs, err := s.Collector(name)
+		if err != nil {
+			writeError(w, statusFor(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, cs)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	var err error
+	switch action {
+	case "trigger":
+		err = s.Trigger(name)
+	case "pause":
+		err = s.Pause(name)
+	case "resume":
+		err = s.Resume(name)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown action: "+action))
+		return
+	}
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	status := http.StatusOK
+	if action == "trigger" {
+		status = http.StatusAccepted
+	}
+	cs, _ := s.Collector(name)
+	writeJSON(w, status, cs)
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrCollectorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrCollectorRunning), errors.Is(err, ErrCollectorDraining):
+		return http.StatusConflict
+	case errors.Is(err, ErrStopping):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/breaker"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/schedule"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testCollector has no invocation strategy, so every invocation fails.
+type testCollector struct {
+	name    string
+	cron    string
+	group   string
+	splay   time.Duration
+	sourcer invoker.Sourcer
+	sinks   []invoker.Sink
+}
+
+func (t testCollector) Name() string                             { return t.name }
+func (t testCollector) InvocationStrategy() invoker.TypeStrategy { return "" }
+func (t testCollector) Sinks() []invoker.Sink                    { return t.sinks }
+func (t testCollector) DeadLetter() *invoker.DeadLetter          { return nil }
+func (t testCollector) Retry() *retry.Policy                     { return nil }
+func (t testCollector) Breaker() *breaker.Breaker                { return nil }
+func (t testCollector) Timeout() time.Duration                   { return 0 }
+func (t testCollector) Sourcer() invoker.Sourcer                 { return t.sourcer }
+func (t testCollector) SourceGroup() string                      { return t.group }
+func (t testCollector) Storer() invoker.Storer                   { return nil }
+func (t testCollector) Transformer() invoker.Transformer         { return nil }
+
+func (t testCollector) Schedule() invoker.Schedule {
+	if t.cron != "" {
+		return schedule.New(schedule.Config{Cron: &t.cron, Splay: &t.splay})
+	}
+	interval := time.Minute
+	return schedule.New(schedule.Config{Interval: &interval, Splay: &t.splay})
+}
+
+func newTestService(t *testing.T, names ...string) (*Service, *http.ServeMux) {
+	var cs []testCollector
+	for _, n := range names {
+		cs = append(cs, testCollector{name: n})
+	}
+	return newTestServiceFromCollectors(t, cs)
+}
+
+func newTestServiceFromCollectors(t *testing.T, cs []testCollector, opts ...Option) (*Service, *http.ServeMux) {
+	var is []*invoker.Invoker
+	for _, c := range cs {
+		i, err := invoker.New(c, invoker.WithLogger(zap.NewNop()))
+		assert.NoError(t, err)
+		is = append(is, i)
+	}
+
+	s, err := NewService(is, opts...)
+	assert.NoError(t, err)
+
+	mux := http.NewServeMux()
+	s.HandleAdmin(mux)
+	s.HandleHealth(mux)
+	return s, mux
+}
+
+func request(mux *http.ServeMux, method string, path string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+	return rr
+}
+
+func TestNewService_DuplicateNames(t *testing.T) {
+	i, err := invoker.New(testCollector{name: "a"})
+	assert.NoError(t, err)
+
+	_, err = NewService([]*invoker.Invoker{i, i})
+	assert.ErrorIs(t, err, ErrCollectorExists)
+}
+
+func TestService_Admin_List(t *testing.T) {
+	_, mux := newTestService(t, "b", "a")
+
+	rr := request(mux, http.MethodGet, "/admin/collectors")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var css []CollectorStatus
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &css))
+	assert.Equal(t, []CollectorStatus{
+		{Name: "b", Schedule: "1m0s"},
+		{Name: "a", Schedule: "1m0s"},
+	}, css)
+}
+
+func TestService_Admin_NotFound(t *testing.T) {
+	_, mux := newTestService(t, "a")
+
+	rr := request(mux, http.MethodGet, "/admin/collectors/missing")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error": "collector not found"}`, rr.Body.String())
+
+	rr = request(mux, http.MethodPost, "/admin/collectors/a/unknown")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = request(mux, http.MethodGet, "/admin/collectors/a/trigger")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestService_Admin_PauseResume(t *testing.T) {
+	s, mux := newTestService(t, "a")
+
+	rr := request(mux, http.MethodPost, "/admin/collectors/a/pause")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	cs, err := s.Collector("a")
+	assert.NoError(t, err)
+	assert.True(t, cs.Paused)
+
+	// scheduled invocations of a paused collector are skipped
+	_, err = s.start(s.collectors["a"], false)
+	assert.ErrorIs(t, err, errPaused)
+
+	rr = request(mux, http.MethodPost, "/admin/collectors/a/resume")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	cs, err = s.Collector("a")
+	assert.NoError(t, err)
+	assert.False(t, cs.Paused)
+}
+
+func TestService_Admin_Trigger_LastError(t *testing.T) {
+	s, mux := newTestService(t, "a")
+
+	rr := request(mux, http.MethodPost, "/admin/collectors/a/trigger")
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	s.running.Wait()
+
+	cs, err := s.Collector("a")
+	assert.NoError(t, err)
+	assert.NotNil(t, cs.LastRun)
+	assert.NotNil(t, cs.LastErrorAt)
+	assert.Equal(t, "strategy: \"\" not supported", cs.LastError)
+}
+
+func TestService_Trigger_Running(t *testing.T) {
+	s, _ := newTestService(t, "a")
+
+	_, err := s.start(s.collectors["a"], true)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, s.Trigger("a"), ErrCollectorRunning)
+}
+
+func TestService_Shutdown_RejectsTrigger(t *testing.T) {
+	s, _ := newTestService(t, "a")
+
+	assert.NoError(t, s.Shutdown())
+	assert.ErrorIs(t, s.Trigger("a"), ErrStopping)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/turbolytics/latte/internal/invoker"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthConfig controls when the daemon reports itself unhealthy.
+type HealthConfig struct {
+	// FailureThreshold is the number of consecutive failed invocations
+	// after which a collector is unhealthy.
+	FailureThreshold int
+	// MissedRuns is the number of scheduled runs a collector may miss
+	// without a successful invocation before it is stale and unhealthy.
+	MissedRuns int
+	// MaxUnhealthy is the number of unhealthy collectors at which the
+	// daemon is unhealthy.
+	MaxUnhealthy int
+	// PingTimeout bounds the connectivity checks of readiness.
+	PingTimeout time.Duration
+}
+
+func (c *HealthConfig) SetDefaults() {
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 3
+	}
+	if c.MissedRuns == 0 {
+		c.MissedRuns = 3
+	}
+	if c.MaxUnhealthy == 0 {
+		c.MaxUnhealthy = 1
+	}
+	if c.PingTimeout == 0 {
+		c.PingTimeout = 5 * time.Second
+	}
+}
+
+func (c HealthConfig) Validate() error {
+	if c.FailureThreshold < 1 {
+		return fmt.Errorf("health failure threshold: %d must be at least 1", c.FailureThreshold)
+	}
+	if c.MissedRuns < 1 {
+		return fmt.Errorf("health missed runs: %d must be at least 1", c.MissedRuns)
+	}
+	if c.MaxUnhealthy < 1 {
+		return fmt.Errorf("health max unhealthy: %d must be at least 1", c.MaxUnhealthy)
+	}
+	return nil
+}
+
+// CollectorHealth is the health of a single collector.
+type CollectorHealth struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	Stale               bool       `json:"stale"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccess         *time.Time `json:"last_success,omitempty"`
+}
+
+type Health struct {
+	Healthy    bool              `json:"healthy"`
+	Collectors []CollectorHealth `json:"collectors"`
+}
+
+type Readiness struct {
+	Ready  bool              `json:"ready"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// staleAt returns when a collector which last succeeded at t is stale.
+// Paused collectors are never stale.
+func (s *Service) staleAt(c *collector, t time.Time) (time.Time, bool) {
+	sch := c.invoker.Collector.Schedule()
+	if sch == nil || c.paused {
+		return time.Time{}, false
+	}
+
+	if sch.Interval() != nil {
+		return t.Add(time.Duration(s.health.MissedRuns) * *sch.Interval()), true
+	}
+
+	if sch.Cron() != nil {
+		cs, err := cron.ParseStandard(*sch.Cron())
+		if err != nil {
+			return time.Time{}, false
+		}
+		next := t
+		for n := 0; n < s.health.MissedRuns; n++ {
+			next = cs.Next(next)
+		}
+		return next, true
+	}
+	return time.Time{}, false
+}
+
+// Health reports collectors which failed consecutively or have not
+// succeeded within their schedule.
+func (s *Service) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	h := Health{
+		Healthy:    true,
+		Collectors: make([]CollectorHealth, 0, len(s.order)),
+	}
+
+	var unhealthy int
+	for _, name := range s.order {
+		c := s.collectors[name]
+		ch := CollectorHealth{
+			Name:                name,
+			ConsecutiveFailures: c.consecutiveFailures,
+		}
+
+		since := s.startedAt
+		if !c.lastSuccess.IsZero() {
+			t := c.lastSuccess
+			ch.LastSuccess = &t
+			since = t
+		}
+
+		if !since.IsZero() {
+			if at, ok := s.staleAt(c, since); ok && now.After(at) {
+				ch.Stale = true
+			}
+		}
+
+		ch.Healthy = !ch.Stale && c.consecutiveFailures < s.health.FailureThreshold
+		if !ch.Healthy {
+			unhealthy++
+		}
+		h.Collectors = append(h.Collectors, ch)
+	}
+
+	h.Healthy = unhealthy < s.health.MaxUnhealthy
+	return h
+}
+
+// Ready reports if every collector is scheduled and its source and
+// sinks are reachable. Each invoker is pinged until it succeeds once,
+// so collectors added or replaced by a reload are checked before the
+// service is ready again. The service is no longer ready when it
+// begins shutting down.
+func (s *Service) Ready(ctx context.Context) Readiness {
+	s.mu.Lock()
+	started, stopping := !s.startedAt.IsZero(), s.stopping
+	pending := make(map[*collector]*invoker.Invoker)
+	for _, c := range s.collectors {
+		if !c.draining && c.pinged != c.invoker {
+			pending[c] = c.invoker
+		}
+	}
+	s.mu.Unlock()
+
+	switch {
+	case stopping:
+		return Readiness{Errors: map[string]string{"service": ErrStopping.Error()}}
+	case !started:
+		return Readiness{Errors: map[string]string{"service": "collectors not scheduled"}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.health.PingTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make(map[string]string)
+	for c, i := range pending {
+		wg.Add(1)
+		go func(c *collector, i *invoker.Invoker) {
+			defer wg.Done()
+			err := i.Ping(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[i.Collector.Name()] = err.Error()
+				return
+			}
+			s.mu.Lock()
+			c.pinged = i
+			s.mu.Unlock()
+		}(c, i)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return Readiness{Errors: errs}
+	}
+	return Readiness{Ready: true}
+}
+
+// HandleHealth registers /healthz and /readyz on mux.
+func (s *Service) HandleHealth(mux *http.ServeMux) {
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		h := s.Health()
+		writeJSON(w, probeStatus(h.Healthy), h)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		rd := s.Ready(r.Context())
+		writeJSON(w, probeStatus(rd.Ready), rd)
+	})
+}
+
+func probeStatus(ok bool) int {
+	if ok {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/source"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type pingSourcer struct {
+	err error
+}
+
+func (p pingSourcer) Source(ctx context.Context) (record.Result, error) { return nil, nil }
+func (p pingSourcer) Type() source.Type                                 { return "tester" }
+func (p pingSourcer) WindowDuration() *time.Duration                    { return nil }
+func (p pingSourcer) Ping(ctx context.Context) error                    { return p.err }
+
+func TestHealthConfig_Validate(t *testing.T) {
+	c := HealthConfig{MissedRuns: -1}
+	c.SetDefaults()
+	assert.EqualError(t, c.Validate(), "health missed runs: -1 must be at least 1")
+}
+
+func TestService_Health(t *testing.T) {
+	testCases := []struct {
+		name         string
+		maxUnhealthy int
+		failures     int
+		startedAgo   time.Duration
+		paused       bool
+		cron         string
+		healthy      bool
+		stale        bool
+	}{
+		{
+			name:       "healthy",
+			startedAgo: time.Minute,
+			healthy:    true,
+		},
+		{
+			name:       "consecutive_failures",
+			startedAgo: time.Minute,
+			failures:   3,
+		},
+		{
+			name:         "max_unhealthy_not_reached",
+			maxUnhealthy: 2,
+			startedAgo:   time.Minute,
+			failures:     3,
+			healthy:      true,
+		},
+		{
+			name:       "stale_interval",
+			startedAgo: 4 * time.Minute,
+			stale:      true,
+		},
+		{
+			name:       "stale_cron",
+			cron:       "*/5 * * * *",
+			startedAgo: 16 * time.Minute,
+			stale:      true,
+		},
+		{
+			name:       "paused_not_stale",
+			startedAgo: time.Hour,
+			paused:     true,
+			healthy:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestServiceFromCollectors(t,
+				[]testCollector{
+					{name: "a"},
+					{name: "b", cron: tc.cron},
+				},
+				WithHealth(HealthConfig{MaxUnhealthy: tc.maxUnhealthy}),
+			)
+
+			now := time.Now()
+			s.startedAt = now.Add(-tc.startedAgo)
+			s.collectors["a"].lastSuccess = now
+			s.collectors["b"].consecutiveFailures = tc.failures
+			s.collectors["b"].paused = tc.paused
+
+			h := s.Health()
+			assert.Equal(t, tc.healthy, h.Healthy)
+			assert.True(t, h.Collectors[0].Healthy)
+			assert.Equal(t, tc.stale, h.Collectors[1].Stale)
+			assert.Equal(t, tc.failures, h.Collectors[1].ConsecutiveFailures)
+		})
+	}
+}
+
+func TestService_Ready(t *testing.T) {
+	src := &pingSourcer{err: errors.New("connection refused")}
+	s, mux := newTestServiceFromCollectors(t, []testCollector{
+		{name: "a", sourcer: src},
+	})
+
+	rr := request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"ready": false, "errors": {"service": "collectors not scheduled"}}`, rr.Body.String())
+
+	s.startedAt = time.Now()
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"ready": false, "errors": {"a": "source: connection refused"}}`, rr.Body.String())
+
+	src.err = nil
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var rd Readiness
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rd))
+	assert.True(t, rd.Ready)
+
+	// a ready collector is not pinged again
+	src.err = errors.New("connection refused")
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// collectors added or replaced while running are pinged
+	added := &pingSourcer{err: errors.New("no route to host")}
+	i, err := invoker.New(testCollector{name: "b", sourcer: added}, invoker.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Add(i))
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"ready": false, "errors": {"b": "source: no route to host"}}`, rr.Body.String())
+
+	added.err = nil
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	i, err = invoker.New(testCollector{name: "a", sourcer: src}, invoker.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+	assert.NoError(t, s.Replace(i))
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"ready": false, "errors": {"a": "source: connection refused"}}`, rr.Body.String())
+
+	// readiness is dropped on shutdown so traffic drains
+	assert.NoError(t, s.Shutdown())
+	rr = request(mux, http.MethodGet, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestService_Healthz(t *testing.T) {
+	s, mux := newTestService(t, "a")
+	s.startedAt = time.Now()
+
+	rr := request(mux, http.MethodGet, "/healthz")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	s.collectors["a"].consecutiveFailures = 3
+	rr = request(mux, http.MethodGet, "/healthz")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
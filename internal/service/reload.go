@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/turbolytics/latte/internal/invoker"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Loader initializes the invoker of a collector config. With validate
+// the config is only checked, sinks and sources are not connected and
+// the invoker is discarded without being closed.
+type Loader func(bs []byte, validate bool) (*invoker.Invoker, error)
+
+// errRestore is returned when neither the changed nor the previous
+// version of a config could be loaded. The collector is removed and
+// the file is retried on the next reload.
+var errRestore = errors.New("restoring previous config")
+
+// loaded is a config file and the collector it defines.
+type loaded struct {
+	sum  [32]byte
+	bs   []byte
+	name string
+}
+
+// Reloader keeps the collectors of a service in sync with the config
+// files matching a glob. Invalid configs are rejected and logged, the
+// previous version of the collector keeps running. A valid change
+// drains the collector before its new config is loaded.
+type Reloader struct {
+	glob    string
+	load    Loader
+	service *Service
+	logger  *zap.Logger
+
+	files map[string]loaded
+	// rejected tracks the checksum of invalid files so they are only
+	// reported once per change.
+	rejected map[string][32]byte
+}
+
+// Reload applies the added, changed and removed config files to the
+// service and returns the errors of the rejected files.
+func (r *Reloader) Reload() error {
+	paths, err := filepath.Glob(r.glob)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		seen[path] = struct{}{}
+		if err := r.reloadFile(path); err != nil {
+			errs = append(errs, fmt.Errorf("config: %q: %w", path, err))
+		}
+	}
+
+	for path, l := range r.files {
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		r.logger.Info(
+			"service.Reloader",
+			zap.String("msg", "config removed"),
+			zap.String("path", path),
+			zap.String("name", l.name),
+		)
+		if err := r.service.Remove(l.name); err != nil {
+			errs = append(errs, fmt.Errorf("config: %q: %w", path, err))
+		}
+		delete(r.files, path)
+	}
+
+	for path := range r.rejected {
+		if _, ok := seen[path]; !ok {
+			delete(r.rejected, path)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *Reloader) reloadFile(path string) error {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(bs)
+
+	prev, known := r.files[path]
+	if known && prev.sum == sum {
+		return nil
+	}
+	if s, ok := r.rejected[path]; ok && s == sum {
+		return nil
+	}
+
+	err = r.apply(path, prev, known, bs, sum)
+	if err != nil {
+		if !errors.Is(err, errRestore) {
+			r.rejected[path] = sum
+		}
+		r.logger.Error(
+			"service.Reloader",
+			zap.String("msg", "config rejected"),
+			zap.String("path", path),
+			zap.Error(err),
+		)
+		return err
+	}
+	delete(r.rejected, path)
+	return nil
+}
+
+func (r *Reloader) apply(path string, prev loaded, known bool, bs []byte, sum [32]byte) error {
+	if !known {
+		i, err := r.load(bs, false)
+		if err != nil {
+			return err
+		}
+		if err := r.service.Add(i); err != nil {
+			r.service.close(i)
+			return err
+		}
+		r.accept(path, bs, sum, i.Collector.Name())
+		return nil
+	}
+
+	// the running collector is only touched once the change is valid
+	v, err := r.load(bs, true)
+	if err != nil {
+		return err
+	}
+	if name := v.Collector.Name(); name != prev.name {
+		if _, err := r.service.Collector(name); err == nil {
+			return fmt.Errorf("collector: %q: %w", name, ErrCollectorExists)
+		}
+	}
+
+	// the previous invoker is closed before its replacement is built,
+	// so stateful sinks are never open twice.
+	if err := r.service.Drain(prev.name); err != nil {
+		return err
+	}
+
+	i, err := r.load(bs, false)
+	if err == nil {
+		if err = r.swap(prev.name, i); err != nil {
+			r.service.close(i)
+		}
+	}
+	if err != nil {
+		return errors.Join(err, r.restore(path, prev))
+	}
+
+	r.accept(path, bs, sum, i.Collector.Name())
+	return nil
+}
+
+// swap installs i in place of the drained collector name.
+func (r *Reloader) swap(name string, i *invoker.Invoker) error {
+	if i.Collector.Name() == name {
+		return r.service.Replace(i)
+	}
+
+	// the collector was renamed, the new name must be free before the
+	// previous collector is removed.
+	if err := r.service.Add(i); err != nil {
+		return err
+	}
+	if err := r.service.Remove(name); err != nil {
+		r.logger.Error(
+			"service.Reloader",
+			zap.String("msg", "removing renamed collector"),
+			zap.String("name", name),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// restore rebuilds the drained collector from the previous version of
+// its config.
+func (r *Reloader) restore(path string, prev loaded) error {
+	i, err := r.load(prev.bs, false)
+	if err == nil {
+		if err = r.service.Replace(i); err != nil {
+			r.service.close(i)
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	r.logger.Error(
+		"service.Reloader",
+		zap.String("msg", "restoring previous config failed, collector removed"),
+		zap.String("path", path),
+		zap.String("name", prev.name),
+		zap.Error(err),
+	)
+	delete(r.files, path)
+	if rmErr := r.service.Remove(prev.name); rmErr != nil {
+		err = errors.Join(err, rmErr)
+	}
+	return fmt.Errorf("%w: %w", errRestore, err)
+}
+
+func (r *Reloader) accept(path string, bs []byte, sum [32]byte, name string) {
+	r.logger.Info(
+		"service.Reloader",
+		zap.String("msg", "config loaded"),
+		zap.String("path", path),
+		zap.String("name", name),
+	)
+	r.files[path] = loaded{
+		sum:  sum,
+		bs:   bs,
+		name: name,
+	}
+}
+
+// Watch reloads every interval and on each trigger, ie: SIGHUP, until
+// ctx is done. An interval of zero only reloads on triggers.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, trigger <-chan os.Signal) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-trigger:
+			r.logger.Info(
+				"service.Reloader",
+				zap.String("msg", "reload triggered"),
+			)
+		}
+		// rejected configs are logged by Reload
+		r.Reload()
+	}
+}
+
+type ReloaderOption func(*Reloader)
+
+func ReloaderWithLogger(l *zap.Logger) ReloaderOption {
+	return func(r *Reloader) {
+		r.logger = l
+	}
+}
+
+func NewReloader(glob string, load Loader, s *Service, opts ...ReloaderOption) *Reloader {
+	r := &Reloader{
+		glob:     glob,
+		load:     load,
+		service:  s,
+		logger:   zap.NewNop(),
+		files:    make(map[string]loaded),
+		rejected: make(map[string][32]byte),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"github.com/turbolytics/latte/internal/sink/wal"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLoader names the collector by the first line of the config.
+func testLoader(bs []byte, validate bool) (*invoker.Invoker, error) {
+	name, _, _ := strings.Cut(string(bs), "\n")
+	if name == "invalid" {
+		return nil, errors.New("invalid config")
+	}
+	return invoker.New(testCollector{name: name}, invoker.WithLogger(zap.NewNop()))
+}
+
+func writeConfig(t *testing.T, dir string, file string, content string) {
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+}
+
+func names(s *Service) []string {
+	var ns []string
+	for _, cs := range s.Collectors() {
+		ns = append(ns, cs.Name)
+	}
+	return ns
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(nil)
+	assert.NoError(t, err)
+	r := NewReloader(filepath.Join(dir, "*.yaml"), testLoader, s)
+
+	writeConfig(t, dir, "a.yaml", "a")
+	writeConfig(t, dir, "b.yaml", "b")
+	assert.NoError(t, r.Reload())
+	assert.Equal(t, []string{"a", "b"}, names(s))
+	a := s.collectors["a"].invoker
+
+	// unchanged files are not reloaded
+	assert.NoError(t, r.Reload())
+	assert.Same(t, a, s.collectors["a"].invoker)
+
+	// changed files replace the invoker and keep the collector state
+	assert.NoError(t, s.Pause("a"))
+	writeConfig(t, dir, "a.yaml", "a\n# v2")
+	assert.NoError(t, r.Reload())
+	assert.NotSame(t, a, s.collectors["a"].invoker)
+	assert.True(t, s.collectors["a"].paused)
+
+	// invalid configs are rejected and the previous version is kept
+	a = s.collectors["a"].invoker
+	writeConfig(t, dir, "a.yaml", "invalid")
+	assert.ErrorContains(t, r.Reload(), "invalid config")
+	assert.Same(t, a, s.collectors["a"].invoker)
+	assert.False(t, s.collectors["a"].draining)
+	// and only reported once
+	assert.NoError(t, r.Reload())
+
+	// renaming onto an existing collector is rejected before draining
+	writeConfig(t, dir, "a.yaml", "b")
+	assert.ErrorIs(t, r.Reload(), ErrCollectorExists)
+	assert.Same(t, a, s.collectors["a"].invoker)
+	assert.False(t, s.collectors["a"].draining)
+
+	// duplicate names are rejected
+	writeConfig(t, dir, "c.yaml", "b")
+	assert.ErrorIs(t, r.Reload(), ErrCollectorExists)
+	assert.Equal(t, []string{"a", "b"}, names(s))
+
+	// renamed and removed collectors
+	writeConfig(t, dir, "c.yaml", "c")
+	writeConfig(t, dir, "a.yaml", "d")
+	assert.NoError(t, os.Remove(filepath.Join(dir, "b.yaml")))
+	assert.NoError(t, r.Reload())
+	assert.Equal(t, []string{"d", "c"}, names(s))
+}
+
+func TestReloader_Reload_Running(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(nil)
+	assert.NoError(t, err)
+	r := NewReloader(filepath.Join(dir, "*.yaml"), testLoader, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return !s.startedAt.IsZero()
+	}, time.Second, time.Millisecond)
+
+	// collectors added while running are scheduled and invoked
+	writeConfig(t, dir, "a.yaml", "a")
+	assert.NoError(t, r.Reload())
+	assert.Eventually(t, func() bool {
+		cs, err := s.Collector("a")
+		return err == nil && cs.LastRun != nil && cs.NextRun != nil
+	}, time.Second, time.Millisecond)
+
+	writeConfig(t, dir, "a.yaml", "a\n# v2")
+	assert.NoError(t, r.Reload())
+	assert.Eventually(t, func() bool {
+		cs, err := s.Collector("a")
+		return err == nil && cs.NextRun != nil
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, len(s.scheduler.Jobs()))
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "a.yaml")))
+	assert.NoError(t, r.Reload())
+	assert.Eventually(t, func() bool {
+		return len(s.scheduler.Jobs()) == 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.NoError(t, s.Shutdown())
+}
+
+// events records the order sinks are opened and closed in.
+type events struct {
+	mu sync.Mutex
+	es []string
+}
+
+func (e *events) add(ev string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.es = append(e.es, ev)
+}
+
+func (e *events) list() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.es...)
+}
+
+// versionSink fails to flush until it is healthy.
+type versionSink struct {
+	version string
+	healthy bool
+	events  *events
+
+	pending   []record.Record
+	delivered []record.Record
+}
+
+func (v *versionSink) Write(ctx context.Context, r record.Record) (int, error) {
+	v.pending = append(v.pending, r)
+	return 1, nil
+}
+
+func (v *versionSink) Flush(ctx context.Context) error {
+	defer func() {
+		v.pending = nil
+	}()
+	if !v.healthy {
+		return errors.New("unavailable")
+	}
+	v.delivered = append(v.delivered, v.pending...)
+	return nil
+}
+
+func (v *versionSink) Close() error {
+	v.events.add("close " + v.version)
+	return nil
+}
+
+func (v *versionSink) Type() sink.Type {
+	return "tester"
+}
+
+func TestReloader_Reload_Buffered(t *testing.T) {
+	dir := t.TempDir()
+	walDir := t.TempDir()
+	evs := &events{}
+	sinks := make(map[string]*versionSink)
+
+	// the config is the collector name and the sink version, the v2
+	// sink is healthy. Validation does not open the buffer.
+	load := func(bs []byte, validate bool) (*invoker.Invoker, error) {
+		name, version, _ := strings.Cut(string(bs), "\n")
+		if version == "invalid" {
+			return nil, errors.New("invalid config")
+		}
+		if validate {
+			return invoker.New(testCollector{name: name}, invoker.WithLogger(zap.NewNop()))
+		}
+		vs := &versionSink{
+			version: version,
+			healthy: version == "v2",
+			events:  evs,
+		}
+		w, err := wal.New(vs, sink.Buffer{Path: walDir})
+		if err != nil {
+			return nil, err
+		}
+		evs.add("open " + version)
+		sinks[version] = vs
+		return invoker.New(testCollector{
+			name:  name,
+			sinks: []invoker.Sink{{Name: "buffered", Sinker: w}},
+		}, invoker.WithLogger(zap.NewNop()))
+	}
+
+	s, err := NewService(nil)
+	assert.NoError(t, err)
+	r := NewReloader(filepath.Join(dir, "*.yaml"), load, s)
+
+	writeConfig(t, dir, "a.yaml", "a\nv1")
+	assert.NoError(t, r.Reload())
+
+	// an invocation of v1 is running while the config changes
+	c := s.collectors["a"]
+	i, err := s.start(c, false)
+	assert.NoError(t, err)
+
+	writeConfig(t, dir, "a.yaml", "a\nv2")
+	done := make(chan error)
+	go func() {
+		done <- r.Reload()
+	}()
+
+	// the replacement is not built until the invocation finishes
+	assert.Never(t, func() bool {
+		return len(evs.list()) > 1
+	}, 50*time.Millisecond, time.Millisecond)
+	_, err = s.start(c, true)
+	assert.ErrorIs(t, err, ErrCollectorDraining)
+
+	buffered := i.Collector.Sinks()[0]
+	_, err = buffered.Write(context.Background(), &metric.Metric{Name: "core.users.total"})
+	assert.NoError(t, err)
+	assert.NoError(t, buffered.Flush(context.Background()))
+
+	s.mu.Lock()
+	c.active = nil
+	close(c.idle)
+	s.mu.Unlock()
+	s.running.Done()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"open v1", "close v1", "open v2"}, evs.list())
+
+	// the batch buffered by v1 is delivered by v2
+	assert.NoError(t, s.collectors["a"].invoker.Collector.Sinks()[0].Flush(context.Background()))
+	assert.Equal(t, 1, len(sinks["v2"].delivered))
+	entries, err := os.ReadDir(walDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// an invalid edit never touches the running collector
+	a := s.collectors["a"].invoker
+	writeConfig(t, dir, "a.yaml", "a\ninvalid")
+	assert.ErrorContains(t, r.Reload(), "invalid config")
+	assert.Same(t, a, s.collectors["a"].invoker)
+	assert.Equal(t, []string{"open v1", "close v1", "open v2"}, evs.list())
+
+	assert.NoError(t, s.Shutdown())
+	assert.Equal(t, []string{"open v1", "close v1", "open v2", "close v2"}, evs.list())
+}
@@ -2,71 +2,544 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/turbolytics/latte/internal/invoker"
 	"go.uber.org/zap"
+	"math/rand"
+	"sync"
+	"time"
 )
 
+const defaultGracePeriod = 30 * time.Second
+
+var (
+	ErrShutdownTimeout   = errors.New("shutdown grace period exceeded")
+	ErrStopping          = errors.New("service is shutting down")
+	ErrCollectorNotFound = errors.New("collector not found")
+	ErrCollectorRunning  = errors.New("collector invocation already running")
+	ErrCollectorExists   = errors.New("collector already exists")
+	ErrCollectorDraining = errors.New("collector is being reloaded")
+
+	errPaused = errors.New("collector paused")
+)
+
+// collector tracks the scheduling state of a single invoker. The
+// state is kept when the invoker is replaced on reload.
+type collector struct {
+	name    string
+	invoker *invoker.Invoker
+	job     gocron.Job
+
+	// active is the invoker of the running invocation, if any. idle
+	// is closed once it finishes.
+	active *invoker.Invoker
+	idle   chan struct{}
+	// draining collectors are not invoked until their invoker is
+	// replaced.
+	draining bool
+	// pinged is the last invoker which passed the readiness check.
+	pinged *invoker.Invoker
+
+	paused    bool
+	removed   bool
+	lastRun   time.Time
+	lastErr   error
+	lastErrAt time.Time
+
+	lastSuccess         time.Time
+	consecutiveFailures int
+}
+
 type Service struct {
 	logger    *zap.Logger
-	invokers  []*invoker.Invoker
 	scheduler gocron.Scheduler
+
+	gracePeriod time.Duration
+	health      HealthConfig
+
+	// slots limits the concurrent invocations of all collectors, and
+	// groupSlots of the collectors of each source group.
+	maxConcurrency int
+	groupLimits    map[string]int
+	slots          chan struct{}
+	groupSlots     map[string]chan struct{}
+	// splay returns a random delay up to d.
+	splay func(d time.Duration) time.Duration
+
+	// ctx is the context of all invocations. It is only cancelled
+	// once the grace period is exceeded, so invocations running at
+	// shutdown are able to finish writing to their sinks.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopped is closed when shutdown begins.
+	stopped chan struct{}
+
+	mu         sync.Mutex
+	startedAt  time.Time
+	stopping   bool
+	order      []string
+	collectors map[string]*collector
+	running    sync.WaitGroup
 }
 
-func (s *Service) Shutdown() {
-	s.logger.Info("shutdown")
-	defer s.scheduler.Shutdown()
+// Shutdown stops scheduling invocations, waits up to the grace period
+// for running invocations to finish and then closes every collector.
+// Invocations still running after the grace period are cancelled and
+// ErrShutdownTimeout is returned.
+func (s *Service) Shutdown() error {
+	s.logger.Info(
+		"shutdown",
+		zap.Duration("grace_period", s.gracePeriod),
+	)
+
+	s.mu.Lock()
+	if !s.stopping {
+		s.stopping = true
+		close(s.stopped)
+	}
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(s.gracePeriod)
+
+	var errs []error
+	if err := s.scheduler.Shutdown(); err != nil {
+		errs = append(errs, fmt.Errorf("scheduler: %w", err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		s.logger.Error(
+			"shutdown",
+			zap.String("msg", "grace period exceeded, cancelling running invocations"),
+		)
+		s.cancel()
+		<-done
+		errs = append(errs, ErrShutdownTimeout)
+	}
+	s.cancel()
+
 	// close each collector
-	for _, i := range s.invokers {
-		i.Close()
+	for _, i := range s.invokers() {
+		if err := i.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("collector: %q: %w", i.Collector.Name(), err))
+		}
 	}
 
+	return errors.Join(errs...)
 }
 
-func (s *Service) Run(ctx context.Context) error {
-	s.logger.Info("run")
-	// iterate all collectors and invoke for the initial invocation
-	for _, i := range s.invokers {
-		go i.InvokeHandleError(ctx)
+// invokers returns the current invoker of every collector in order.
+func (s *Service) invokers() []*invoker.Invoker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var is []*invoker.Invoker
+	for _, name := range s.order {
+		c := s.collectors[name]
+		// drained invokers are already closed
+		if c.draining {
+			continue
+		}
+		is = append(is, c.invoker)
 	}
+	return is
+}
 
-	for _, i := range s.invokers {
-		iCopy := i
-		sch := i.Collector.Schedule()
+// start reserves an invocation of c and returns the invoker to run.
+// Scheduled invocations of paused collectors are skipped, and a
+// collector never runs concurrently with itself.
+func (s *Service) start(c *collector, manual bool) (*invoker.Invoker, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		var jd gocron.JobDefinition
-		if sch.Interval() != nil {
-			jd = gocron.DurationJob(
-				*(sch.Interval()),
-			)
-		} else if sch.Cron() != nil {
-			jd = gocron.CronJob(
-				*(sch.Cron()),
-				false,
+	if s.stopping {
+		return nil, ErrStopping
+	}
+	if c.removed {
+		return nil, ErrCollectorNotFound
+	}
+	if c.draining {
+		return nil, ErrCollectorDraining
+	}
+	if c.paused && !manual {
+		return nil, errPaused
+	}
+	if c.active != nil {
+		return nil, ErrCollectorRunning
+	}
+
+	c.active = c.invoker
+	c.idle = make(chan struct{})
+	s.running.Add(1)
+	return c.invoker, nil
+}
+
+// invoke runs a reserved invocation and records its outcome. An
+// invoker which was replaced or removed while running is closed once
+// its invocation finishes.
+func (s *Service) invoke(c *collector, i *invoker.Invoker) {
+	defer s.running.Done()
+
+	start := time.Now()
+	release, err := s.acquire(i.Collector.SourceGroup())
+	if err == nil {
+		if waited := time.Since(start); waited > time.Second {
+			s.logger.Info(
+				"service.invoke",
+				zap.String("msg", "waited for concurrency limit"),
+				zap.String("name", c.name),
+				zap.Duration("waited", waited),
 			)
 		}
+		start = time.Now()
+		err = i.Invoke(s.ctx)
+		release()
+	}
+	if err != nil {
+		s.logger.Error(err.Error())
+	}
 
-		_, err := s.scheduler.NewJob(
-			jd,
-			gocron.NewTask(
-				func(ctx context.Context) {
-					iCopy.InvokeHandleError(ctx)
-				},
-				ctx,
-			),
-			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	s.mu.Lock()
+	c.active = nil
+	close(c.idle)
+	switch {
+	case errors.Is(err, ErrStopping):
+		// never invoked, waiting for a slot was abandoned
+	case err != nil:
+		c.lastRun = start
+		c.lastErr = err
+		c.lastErrAt = time.Now()
+		c.consecutiveFailures++
+	default:
+		c.lastRun = start
+		c.lastSuccess = time.Now()
+		c.consecutiveFailures = 0
+	}
+	retired := c.removed || c.invoker != i
+	s.mu.Unlock()
+
+	if retired {
+		s.close(i)
+	}
+}
+
+func (s *Service) close(i *invoker.Invoker) {
+	if err := i.Close(); err != nil {
+		s.logger.Error(
+			"service.close",
+			zap.String("name", i.Collector.Name()),
+			zap.Error(err),
 		)
-		if err != nil {
+	}
+}
+
+// acquire waits for a slot of the source group, then for a global
+// slot, so collectors of a saturated group do not hold global slots.
+func (s *Service) acquire(group string) (func(), error) {
+	var held []chan struct{}
+	release := func() {
+		for _, sem := range held {
+			<-sem
+		}
+	}
+
+	sems := []chan struct{}{s.groupSlots[group], s.slots}
+	for _, sem := range sems {
+		if sem == nil {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-s.stopped:
+			release()
+			return nil, ErrStopping
+		}
+	}
+	return release, nil
+}
+
+// wait delays an invocation by the splay of its schedule. It returns
+// false if the service began shutting down.
+func (s *Service) wait(c *collector) bool {
+	s.mu.Lock()
+	sch := c.invoker.Collector.Schedule()
+	s.mu.Unlock()
+
+	if sch == nil || sch.Splay() <= 0 {
+		return true
+	}
+
+	t := time.NewTimer(s.splay(sch.Splay()))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-s.stopped:
+		return false
+	}
+}
+
+// scheduled is the task run by the scheduler and at startup.
+func (s *Service) scheduled(c *collector) {
+	if !s.wait(c) {
+		return
+	}
+
+	i, err := s.start(c, false)
+	if errors.Is(err, ErrCollectorNotFound) {
+		s.unschedule(c)
+		return
+	}
+	if err != nil {
+		s.logger.Debug(
+			"service.scheduled",
+			zap.String("name", c.name),
+			zap.Error(err),
+		)
+		return
+	}
+	s.invoke(c, i)
+}
+
+func jobDefinition(sch invoker.Schedule) gocron.JobDefinition {
+	var jd gocron.JobDefinition
+	if sch.Interval() != nil {
+		jd = gocron.DurationJob(
+			*(sch.Interval()),
+		)
+	} else if sch.Cron() != nil {
+		jd = gocron.CronJob(
+			*(sch.Cron()),
+			false,
+		)
+	}
+	return jd
+}
+
+// schedule creates the job of c, or updates it when c is already
+// scheduled. It must be called with s.mu held.
+func (s *Service) schedule(c *collector) error {
+	jd := jobDefinition(c.invoker.Collector.Schedule())
+	task := gocron.NewTask(
+		func() {
+			s.scheduled(c)
+		},
+	)
+	opts := []gocron.JobOption{
+		gocron.WithName(c.name),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	}
+
+	var job gocron.Job
+	var err error
+	if c.job != nil {
+		job, err = s.scheduler.Update(c.job.ID(), jd, task, opts...)
+	} else {
+		job, err = s.scheduler.NewJob(jd, task, opts...)
+	}
+	if err != nil {
+		return err
+	}
+	c.job = job
+	return nil
+}
+
+// Run schedules all collectors and blocks until ctx is done. Running
+// invocations are not cancelled with ctx, call Shutdown to wait for
+// them.
+func (s *Service) Run(ctx context.Context) error {
+	s.logger.Info("run")
+
+	s.mu.Lock()
+	for _, name := range s.order {
+		if err := s.schedule(s.collectors[name]); err != nil {
+			s.mu.Unlock()
 			return err
 		}
 	}
 
+	// iterate all collectors and invoke for the initial invocation
+	for _, name := range s.order {
+		go s.scheduled(s.collectors[name])
+	}
+
 	s.scheduler.Start()
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
 	<-ctx.Done()
 
 	return nil
 }
 
+// Add registers a collector. Once the service is running the
+// collector is scheduled and invoked immediately.
+func (s *Service) Add(i *invoker.Invoker) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return ErrStopping
+	}
+
+	name := i.Collector.Name()
+	if _, ok := s.collectors[name]; ok {
+		return fmt.Errorf("collector: %q: %w", name, ErrCollectorExists)
+	}
+
+	c := &collector{
+		name:    name,
+		invoker: i,
+	}
+
+	if !s.startedAt.IsZero() {
+		if err := s.schedule(c); err != nil {
+			return err
+		}
+		go s.scheduled(c)
+	}
+
+	s.collectors[name] = c
+	s.order = append(s.order, name)
+	return nil
+}
+
+// Replace swaps the invoker of a collector, keeping its scheduling
+// state. A running invocation of the previous invoker finishes before
+// it is closed.
+func (s *Service) Replace(i *invoker.Invoker) error {
+	s.mu.Lock()
+
+	if s.stopping {
+		s.mu.Unlock()
+		return ErrStopping
+	}
+
+	name := i.Collector.Name()
+	c, ok := s.collectors[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("collector: %q: %w", name, ErrCollectorNotFound)
+	}
+
+	prev := c.invoker
+	c.invoker = i
+	if c.job != nil {
+		if err := s.schedule(c); err != nil {
+			c.invoker = prev
+			s.mu.Unlock()
+			return err
+		}
+	}
+	drained := c.draining
+	c.draining = false
+	active := c.active
+	s.mu.Unlock()
+
+	if !drained && prev != active {
+		s.close(prev)
+	}
+	return nil
+}
+
+// Drain stops invoking a collector, waits for its running invocation
+// to finish and closes its invoker. Stateful sinks, ie: audit logs and
+// write-ahead logs, are released before a replacement opens them. The
+// collector is invoked again once its invoker is replaced.
+func (s *Service) Drain(name string) error {
+	s.mu.Lock()
+
+	if s.stopping {
+		s.mu.Unlock()
+		return ErrStopping
+	}
+
+	c, ok := s.collectors[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("collector: %q: %w", name, ErrCollectorNotFound)
+	}
+	if c.draining {
+		s.mu.Unlock()
+		return fmt.Errorf("collector: %q: %w", name, ErrCollectorDraining)
+	}
+
+	c.draining = true
+	i := c.invoker
+	var idle chan struct{}
+	if c.active != nil {
+		idle = c.idle
+	}
+	s.mu.Unlock()
+
+	if idle != nil {
+		<-idle
+	}
+	s.close(i)
+	return nil
+}
+
+// Remove unschedules a collector. A running invocation finishes before
+// the collector is closed.
+func (s *Service) Remove(name string) error {
+	s.mu.Lock()
+
+	c, ok := s.collectors[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("collector: %q: %w", name, ErrCollectorNotFound)
+	}
+
+	c.removed = true
+	delete(s.collectors, name)
+	for idx, n := range s.order {
+		if n == name {
+			s.order = append(s.order[:idx], s.order[idx+1:]...)
+			break
+		}
+	}
+	active := c.active
+	drained := c.draining
+	s.mu.Unlock()
+
+	s.unschedule(c)
+	if !drained && c.invoker != active {
+		s.close(c.invoker)
+	}
+	return nil
+}
+
+// unschedule removes the job of c. gocron applies job changes
+// asynchronously, so a job which is not found yet is removed by its
+// own task the next time it runs.
+func (s *Service) unschedule(c *collector) {
+	s.mu.Lock()
+	job := c.job
+	s.mu.Unlock()
+	if job == nil {
+		return
+	}
+
+	err := s.scheduler.RemoveJob(job.ID())
+	if err != nil && !errors.Is(err, gocron.ErrJobNotFound) {
+		s.logger.Error(
+			"service.unschedule",
+			zap.String("name", c.name),
+			zap.Error(err),
+		)
+	}
+}
+
 type Option func(*Service)
 
 func WithLogger(l *zap.Logger) Option {
@@ -75,20 +548,83 @@ func WithLogger(l *zap.Logger) Option {
 	}
 }
 
-func NewService(is []*invoker.Invoker, opts ...Option) (*Service, error) {
-	sch, err := gocron.NewScheduler()
-	if err != nil {
-		return nil, err
+// WithGracePeriod sets how long Shutdown waits for running invocations.
+func WithGracePeriod(d time.Duration) Option {
+	return func(s *Service) {
+		s.gracePeriod = d
 	}
+}
 
+// WithMaxConcurrency limits the concurrent invocations of all
+// collectors, zero is unlimited.
+func WithMaxConcurrency(n int) Option {
+	return func(s *Service) {
+		s.maxConcurrency = n
+	}
+}
+
+// WithGroupLimits limits the concurrent invocations of the collectors
+// of each source group.
+func WithGroupLimits(limits map[string]int) Option {
+	return func(s *Service) {
+		s.groupLimits = limits
+	}
+}
+
+func WithHealth(c HealthConfig) Option {
+	return func(s *Service) {
+		s.health = c
+	}
+}
+
+func NewService(is []*invoker.Invoker, opts ...Option) (*Service, error) {
 	s := &Service{
-		invokers:  is,
-		scheduler: sch,
+		gracePeriod: defaultGracePeriod,
+		collectors:  make(map[string]*collector),
+		groupSlots:  make(map[string]chan struct{}),
+		stopped:     make(chan struct{}),
+		logger:      zap.NewNop(),
+		splay: func(d time.Duration) time.Duration {
+			return time.Duration(rand.Int63n(int64(d)))
+		},
 	}
 
 	for _, opt := range opts {
 		opt(s)
 	}
 
+	s.health.SetDefaults()
+	if err := s.health.Validate(); err != nil {
+		return nil, err
+	}
+
+	if s.maxConcurrency < 0 {
+		return nil, fmt.Errorf("max concurrency: %d must be positive", s.maxConcurrency)
+	}
+	if s.maxConcurrency > 0 {
+		s.slots = make(chan struct{}, s.maxConcurrency)
+	}
+	for group, limit := range s.groupLimits {
+		if limit < 1 {
+			return nil, fmt.Errorf("source group: %q limit: %d must be at least 1", group, limit)
+		}
+		s.groupSlots[group] = make(chan struct{}, limit)
+	}
+
+	for _, i := range is {
+		if err := s.Add(i); err != nil {
+			return nil, err
+		}
+	}
+
+	sch, err := gocron.NewScheduler(
+		gocron.WithStopTimeout(s.gracePeriod),
+	)
+	if err != nil {
+		return nil, err
+	}
+	s.scheduler = sch
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
 	return s, nil
 }
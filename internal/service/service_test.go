@@ -0,0 +1,92 @@
+package service
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewService_InvalidLimits(t *testing.T) {
+	_, err := NewService(nil, WithMaxConcurrency(-1))
+	assert.EqualError(t, err, "max concurrency: -1 must be positive")
+
+	_, err = NewService(nil, WithGroupLimits(map[string]int{"db": 0}))
+	assert.EqualError(t, err, "source group: \"db\" limit: 0 must be at least 1")
+}
+
+func TestService_acquire_Global(t *testing.T) {
+	s, err := NewService(nil, WithMaxConcurrency(1))
+	assert.NoError(t, err)
+
+	release, err := s.acquire("")
+	assert.NoError(t, err)
+
+	acquired := make(chan error)
+	go func() {
+		release, err := s.acquire("")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the global limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release()
+	assert.NoError(t, <-acquired)
+}
+
+func TestService_acquire_Group(t *testing.T) {
+	s, err := NewService(nil,
+		WithMaxConcurrency(2),
+		WithGroupLimits(map[string]int{"db": 1}),
+	)
+	assert.NoError(t, err)
+
+	_, err = s.acquire("db")
+	assert.NoError(t, err)
+
+	acquired := make(chan error)
+	go func() {
+		_, err := s.acquire("db")
+		acquired <- err
+	}()
+
+	// a collector waiting on its group does not hold a global slot
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, len(s.slots))
+	release, err := s.acquire("other")
+	assert.NoError(t, err)
+	release()
+
+	// waiting is abandoned on shutdown
+	assert.NoError(t, s.Shutdown())
+	assert.ErrorIs(t, <-acquired, ErrStopping)
+}
+
+func TestService_wait_Splay(t *testing.T) {
+	s, _ := newTestServiceFromCollectors(t, []testCollector{
+		{name: "a"},
+		{name: "b", splay: time.Hour},
+	})
+
+	var splays []time.Duration
+	s.splay = func(d time.Duration) time.Duration {
+		splays = append(splays, d)
+		return time.Millisecond
+	}
+
+	assert.True(t, s.wait(s.collectors["a"]))
+	assert.True(t, s.wait(s.collectors["b"]))
+	assert.Equal(t, []time.Duration{time.Hour}, splays)
+
+	s.splay = func(d time.Duration) time.Duration {
+		return d
+	}
+	go s.Shutdown()
+	assert.False(t, s.wait(s.collectors["b"]))
+}
@@ -0,0 +1,31 @@
+package sink
+
+import "fmt"
+
+const defaultBufferMaxBytes = 100 << 20
+
+// Buffer configures an on-disk write-ahead log in front of a sink.
+type Buffer struct {
+	// Path is the directory the log segments are stored in.
+	Path string
+	// MaxBytes bounds the disk used by the log. The oldest segments
+	// are dropped once it is exceeded.
+	MaxBytes int64 `yaml:"max_bytes"`
+}
+
+func (b Buffer) Validate() error {
+	if b.Path == "" {
+		return fmt.Errorf("buffer requires a %q", "path")
+	}
+
+	if b.MaxBytes < 0 {
+		return fmt.Errorf("buffer max_bytes: %d must be positive", b.MaxBytes)
+	}
+	return nil
+}
+
+func (b *Buffer) SetDefaults() {
+	if b.MaxBytes == 0 {
+		b.MaxBytes = defaultBufferMaxBytes
+	}
+}
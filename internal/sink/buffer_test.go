@@ -0,0 +1,22 @@
+package sink
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConfig_Validate_Buffer(t *testing.T) {
+	for _, typ := range []Type{TypeOTLP, TypeStatsD} {
+		err := Config{
+			Type:   typ,
+			Buffer: &Buffer{Path: "/tmp/latte/wal"},
+		}.Validate()
+		assert.EqualError(t, err, `buffer is not supported by the "`+string(typ)+`" sink`)
+	}
+
+	err := Config{
+		Type:   TypeHTTP,
+		Buffer: &Buffer{Path: "/tmp/latte/wal"},
+	}.Validate()
+	assert.NoError(t, err)
+}
@@ -1,28 +1,154 @@
 package sink
 
 import (
+	"fmt"
+	"github.com/turbolytics/latte/internal/breaker"
 	"github.com/turbolytics/latte/internal/collector/template"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 )
 
 type Type string
 
 const (
-	TypeConsole Type = "console"
-	TypeHTTP    Type = "http"
-	TypeKafka   Type = "kafka"
-	TypeFile    Type = "file"
-	TypeS3      Type = "s3"
+	TypeConsole       Type = "console"
+	TypeHTTP          Type = "http"
+	TypeKafka         Type = "kafka"
+	TypeFile          Type = "file"
+	TypeS3            Type = "s3"
+	TypeOTLP          Type = "otlp"
+	TypeStatsD        Type = "statsd"
+	TypeInfluxDB      Type = "influxdb"
+	TypePostgres      Type = "postgres"
+	TypeSQL           Type = "sql"
+	TypeWarehouse     Type = "warehouse"
+	TypeNATS          Type = "nats"
+	TypeElasticsearch Type = "elasticsearch"
+	TypeMongoDB       Type = "mongodb"
+	TypePrometheus    Type = "prometheus"
 )
 
 type Config struct {
-	Type   Type
-	Config map[string]any
+	Type    Type
+	Config  map[string]any
+	Filter  *Filter
+	RouteBy *Route `yaml:"route_by"`
+	Fields  *Fields
+	Buffer  *Buffer
+	// Required sinks fail the invocation when they fail. Sinks are
+	// required unless set to false.
+	Required *bool
+	// Retry overrides the collector retry policy for the sink.
+	Retry *retry.Config
+	// CircuitBreaker overrides the collector circuit breaker for the
+	// sink.
+	CircuitBreaker *breaker.Config `yaml:"circuit_breaker"`
+}
+
+func (c Config) IsRequired() bool {
+	return c.Required == nil || *c.Required
+}
+
+func (c Config) Validate() error {
+	if c.Filter != nil {
+		if err := c.Filter.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.RouteBy != nil {
+		if err := c.RouteBy.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Fields != nil {
+		if err := c.Fields.Validate(); err != nil {
+			return err
+		}
+		if err := c.validateFields(); err != nil {
+			return err
+		}
+	}
+
+	if c.Buffer != nil {
+		if err := c.Buffer.Validate(); err != nil {
+			return err
+		}
+		// replayed records are plain maps, otlp and statsd write metrics.
+		if c.Type == TypeOTLP || c.Type == TypeStatsD {
+			return fmt.Errorf("buffer is not supported by the %q sink", c.Type)
+		}
+	}
+
+	if c.Retry != nil {
+		if err := c.Retry.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.CircuitBreaker != nil {
+		if err := c.CircuitBreaker.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keyFields identify a record, keyed sinks derive document ids,
+// filters and primary keys from them.
+var keyFields = []string{"name", "window", "timestamp"}
+
+// validateFields rejects fields on sinks that depend on the shape of
+// the record: otlp and statsd write metrics, prometheus requires the
+// record name and value and keyed sinks require the key fields and
+// tags.
+func (c Config) validateFields() error {
+	switch c.Type {
+	case TypeOTLP, TypeStatsD:
+		return fmt.Errorf("fields are not supported by the %q sink", c.Type)
+	case TypePrometheus:
+		return c.requireFields("name", "value")
+	case TypeElasticsearch, TypeWarehouse, TypePostgres, TypeSQL:
+		return c.requireKey()
+	case TypeMongoDB:
+		if err := c.requireKey(); err != nil {
+			return err
+		}
+		// tags are nested under `tags` by the sink, a projected `tags`
+		// would be written twice.
+		if c.Fields.Writes("tags") {
+			return fmt.Errorf("fields: %q sink reserves record key: %q", c.Type, "tags")
+		}
+	}
+	return nil
+}
+
+func (c Config) requireKey() error {
+	if err := c.requireFields(keyFields...); err != nil {
+		return err
+	}
+	if !c.Fields.PreservesPrefix(record.TagPrefix) {
+		return fmt.Errorf("fields: %q sink requires record keys: %q", c.Type, record.TagPrefix+"*")
+	}
+	return nil
+}
+
+func (c Config) requireFields(keys ...string) error {
+	for _, k := range keys {
+		if !c.Fields.Preserves(k) {
+			return fmt.Errorf("fields: %q sink requires record key: %q", c.Type, k)
+		}
+	}
+	return nil
 }
 
 func ApplyTemplates(c *Config) error {
 	// enabling templating across a couple of fixed, known configuration fields
 	fields := []string{
 		"uri",
+		"token",
 	}
 	for _, field := range fields {
 		if _, hasField := c.Config[field]; hasField {
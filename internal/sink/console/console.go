@@ -4,30 +4,81 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"github.com/turbolytics/latte/internal/encoding"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 )
 
+type Mode string
+
+const (
+	// ModeRaw prints the encoder output of each record.
+	ModeRaw Mode = "raw"
+	// ModeTable prints all records of an invocation as an aligned
+	// table, with one column per tag.
+	ModeTable Mode = "table"
+	// ModePretty prints each record as indented json.
+	ModePretty Mode = "pretty"
+)
+
+// leadingColumns are displayed first, in this order, when present.
+var leadingColumns = []string{"name", "type", "value", "window", "timestamp"}
+
 type config struct {
 	Encoding encoding.Config
+	Mode     Mode
+	// Summary prints a footer with record counts on each Flush.
+	Summary bool
+}
+
+type Option func(*Console)
+
+// WithWriter overrides the default stdout writer.
+func WithWriter(w io.Writer) Option {
+	return func(c *Console) {
+		c.w = bufio.NewWriter(w)
+	}
 }
 
 type Console struct {
+	config  config
+	counts  map[string]int
 	encoder encoding.Encoder
-	w       io.Writer
+	records []map[string]any
+	w       *bufio.Writer
 }
 
 func (c *Console) Close() error {
-	return nil
+	return c.w.Flush()
 }
 
 func (c *Console) Flush(ctx context.Context) error {
-	return nil
+	if c.config.Mode == ModeTable {
+		if err := c.writeTable(); err != nil {
+			return err
+		}
+	}
+
+	if c.config.Summary {
+		if err := c.writeSummary(); err != nil {
+			return err
+		}
+	}
+
+	c.records = nil
+	c.counts = make(map[string]int)
+	return c.w.Flush()
 }
 
 func (c *Console) Type() sink.Type {
@@ -35,27 +86,155 @@ func (c *Console) Type() sink.Type {
 }
 
 func (c *Console) Write(ctx context.Context, r record.Record) (int, error) {
+	m := r.Map()
+
+	name, _ := m["name"].(string)
+	c.counts[name]++
+
+	switch c.config.Mode {
+	case ModeTable:
+		c.records = append(c.records, m)
+		return 0, nil
+	case ModePretty:
+		bs, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return 0, retry.Permanent(err)
+		}
+		return c.writeLine(bs)
+	}
+
 	buf := &bytes.Buffer{}
 	if err := c.encoder.Init(buf); err != nil {
 		return 0, nil
 	}
 
-	if err := c.encoder.Write(r.Map()); err != nil {
-		return 0, err
+	if err := c.encoder.Write(m); err != nil {
+		return 0, retry.Permanent(err)
 	}
 
-	bs := buf.Bytes()
-	fmt.Println(string(bs))
-	return 0, nil
+	return c.writeLine(buf.Bytes())
 }
 
-func NewFromGenericConfig(m map[string]any) (*Console, error) {
+func (c *Console) writeLine(bs []byte) (int, error) {
+	n, err := c.w.Write(bs)
+	if err != nil {
+		return n, err
+	}
+	if !bytes.HasSuffix(bs, []byte("\n")) {
+		_, err = c.w.Write([]byte("\n"))
+	}
+	return n, err
+}
+
+func (c *Console) writeTable() error {
+	if len(c.records) == 0 {
+		return nil
+	}
+
+	cols := columns(c.records)
+	tw := tabwriter.NewWriter(c.w, 0, 0, 2, ' ', 0)
+
+	headers := make([]string, len(cols))
+	for i, col := range cols {
+		headers[i] = strings.ToUpper(strings.TrimPrefix(col, record.TagPrefix))
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, r := range c.records {
+		vals := make([]string, len(cols))
+		for i, col := range cols {
+			vals[i] = format(r[col])
+		}
+		fmt.Fprintln(tw, strings.Join(vals, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func (c *Console) writeSummary() error {
+	var total int
+	names := make([]string, 0, len(c.counts))
+	for name, count := range c.counts {
+		names = append(names, name)
+		total += count
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, c.counts[name]))
+	}
+
+	_, err := fmt.Fprintf(c.w, "-- %d records (%s)\n", total, strings.Join(parts, ", "))
+	return err
+}
+
+// columns returns the union of record keys: the leading columns, then
+// any other non tag keys and finally one column per tag. The uuid is
+// omitted to keep rows readable.
+func columns(records []map[string]any) []string {
+	keys := make(map[string]struct{})
+	for _, r := range records {
+		for k := range r {
+			keys[k] = struct{}{}
+		}
+	}
+	delete(keys, "uuid")
+
+	var cols []string
+	for _, k := range leadingColumns {
+		if _, found := keys[k]; found {
+			cols = append(cols, k)
+			delete(keys, k)
+		}
+	}
+
+	var others, tags []string
+	for k := range keys {
+		if strings.HasPrefix(k, record.TagPrefix) {
+			tags = append(tags, k)
+		} else {
+			others = append(others, k)
+		}
+	}
+	sort.Strings(others)
+	sort.Strings(tags)
+
+	cols = append(cols, others...)
+	return append(cols, tags...)
+}
+
+func format(v any) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case *time.Time:
+		if tv == nil {
+			return ""
+		}
+		return tv.Format(time.RFC3339)
+	case time.Time:
+		return tv.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(tv, 'f', -1, 64)
+	default:
+		return fmt.Sprint(tv)
+	}
+}
+
+func NewFromGenericConfig(m map[string]any, opts ...Option) (*Console, error) {
 	var conf config
 	if err := mapstructure.Decode(m, &conf); err != nil {
 		return nil, err
 	}
 
-	writer := bufio.NewWriter(os.Stdout)
+	switch conf.Mode {
+	case "":
+		conf.Mode = ModeRaw
+	case ModeRaw, ModeTable, ModePretty:
+	default:
+		return nil, fmt.Errorf("console mode: %q not supported", conf.Mode)
+	}
 
 	e, err := encoding.NewEncoder(conf.Encoding)
 
@@ -63,8 +242,16 @@ func NewFromGenericConfig(m map[string]any) (*Console, error) {
 		return nil, err
 	}
 
-	return &Console{
+	c := &Console{
+		config:  conf,
+		counts:  make(map[string]int),
 		encoder: e,
-		w:       writer,
-	}, nil
+		w:       bufio.NewWriter(os.Stdout),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
 }
@@ -0,0 +1,112 @@
+package console
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"testing"
+	"time"
+)
+
+var window = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testMetrics() []*metric.Metric {
+	return []*metric.Metric{
+		{
+			UUID:      "1",
+			Name:      "core.users.total",
+			Type:      metric.TypeCount,
+			Value:     3,
+			Timestamp: window,
+			Tags: map[string]string{
+				"customer": "google",
+				"env":      "prod",
+			},
+		},
+		{
+			UUID:      "2",
+			Name:      "core.users.total",
+			Type:      metric.TypeCount,
+			Value:     12,
+			Timestamp: window,
+			Tags: map[string]string{
+				"customer": "amazon",
+			},
+		},
+	}
+}
+
+func TestConsole_Flush_Table(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c, err := NewFromGenericConfig(map[string]any{
+		"mode":    "table",
+		"summary": true,
+	}, WithWriter(buf))
+	assert.NoError(t, err)
+
+	for _, m := range testMetrics() {
+		_, err := c.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+
+	// table mode only writes on flush
+	assert.Equal(t, "", buf.String())
+	assert.NoError(t, c.Flush(context.Background()))
+
+	assert.Equal(t, ""+
+		"NAME              TYPE   VALUE  WINDOW  TIMESTAMP             CUSTOMER  ENV\n"+
+		"core.users.total  COUNT  3              2024-01-01T00:00:00Z  google    prod\n"+
+		"core.users.total  COUNT  12             2024-01-01T00:00:00Z  amazon    \n"+
+		"-- 2 records (core.users.total=2)\n",
+		buf.String(),
+	)
+}
+
+func TestConsole_Write_Pretty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c, err := NewFromGenericConfig(map[string]any{
+		"mode": "pretty",
+	}, WithWriter(buf))
+	assert.NoError(t, err)
+
+	_, err = c.Write(context.Background(), &metric.Metric{
+		Name:      "core.users.total",
+		Timestamp: window,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, c.Flush(context.Background()))
+
+	assert.Equal(t, `{
+  "name": "core.users.total",
+  "timestamp": "2024-01-01T00:00:00Z",
+  "type": "",
+  "uuid": "",
+  "value": 0,
+  "window": null
+}
+`, buf.String())
+}
+
+func TestConsole_Write_Raw(t *testing.T) {
+	buf := &bytes.Buffer{}
+	c, err := NewFromGenericConfig(map[string]any{}, WithWriter(buf))
+	assert.NoError(t, err)
+
+	for _, m := range testMetrics() {
+		_, err := c.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, c.Flush(context.Background()))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, string(lines[0]), `"tag.customer":"google"`)
+}
+
+func TestNewFromGenericConfig_InvalidMode(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"mode": "csv",
+	})
+	assert.EqualError(t, err, "console mode: \"csv\" not supported")
+}
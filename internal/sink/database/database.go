@@ -0,0 +1,333 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	_ "github.com/lib/pq"
+	_ "github.com/marcboeker/go-duckdb"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+)
+
+// TagsKey is a virtual record key which renders all `tag.*` values
+// of a record as a single JSON object.
+const TagsKey = "tags"
+
+type Placeholder string
+
+const (
+	PlaceholderDollar   Placeholder = "dollar"
+	PlaceholderQuestion Placeholder = "question"
+)
+
+type Column struct {
+	// Name of the database column.
+	Name string
+	// From is the record key the column value is read from.
+	From string
+}
+
+type config struct {
+	Driver      string
+	URI         string
+	Table       string
+	Columns     []Column
+	UpsertKey   []string `mapstructure:"upsert_key"`
+	Placeholder Placeholder
+}
+
+func (c *config) init() error {
+	if c.Driver == "" {
+		return fmt.Errorf("database sink requires a %q", "driver")
+	}
+
+	if c.Table == "" {
+		return fmt.Errorf("database sink requires a %q", "table")
+	}
+
+	if len(c.Columns) == 0 {
+		return fmt.Errorf("database sink requires at least one column")
+	}
+
+	cols := make(map[string]struct{})
+	for i, col := range c.Columns {
+		if col.Name == "" {
+			return fmt.Errorf("database sink column %d requires a %q", i, "name")
+		}
+		if col.From == "" {
+			c.Columns[i].From = col.Name
+		}
+		cols[col.Name] = struct{}{}
+	}
+
+	for _, k := range c.UpsertKey {
+		if _, found := cols[k]; !found {
+			return fmt.Errorf("database sink upsert_key column: %q is not a configured column", k)
+		}
+	}
+
+	switch c.Placeholder {
+	case "":
+		c.Placeholder = PlaceholderQuestion
+		if c.Driver == "postgres" {
+			c.Placeholder = PlaceholderDollar
+		}
+	case PlaceholderDollar, PlaceholderQuestion:
+	default:
+		return fmt.Errorf("database sink placeholder: %q not supported", c.Placeholder)
+	}
+
+	return nil
+}
+
+type Option func(*Database)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(d *Database) {
+		d.logger = l
+	}
+}
+
+// WithDB uses an existing connection instead of opening one from the
+// configured driver and uri.
+func WithDB(db *sql.DB) Option {
+	return func(d *Database) {
+		d.db = db
+	}
+}
+
+// Database buffers records and writes them to a table in a single
+// transaction on each Flush. When an upsert key is configured, rows
+// which conflict on the key are replaced, so re-running a window
+// does not duplicate rows.
+type Database struct {
+	config   config
+	db       *sql.DB
+	rows     [][]any
+	sinkType sink.Type
+	stmt     string
+
+	logger *zap.Logger
+}
+
+func (d *Database) Ping(ctx context.Context) error {
+	if d.db == nil {
+		return nil
+	}
+	return d.db.PingContext(ctx)
+}
+
+func (d *Database) Close() error {
+	if d.db == nil {
+		return nil
+	}
+	return d.db.Close()
+}
+
+func (d *Database) Flush(ctx context.Context) (err error) {
+	if len(d.rows) == 0 {
+		return nil
+	}
+	defer func() {
+		d.rows = nil
+	}()
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, d.stmt)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range d.rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+
+	d.logger.Debug(
+		"sinks.Database.flush",
+		zap.String("table", d.config.Table),
+		zap.Int("rows", len(d.rows)),
+	)
+
+	return tx.Commit()
+}
+
+func (d *Database) Type() sink.Type {
+	return d.sinkType
+}
+
+func (d *Database) Write(ctx context.Context, r record.Record) (int, error) {
+	row, err := Row(r.Map(), d.config.Columns)
+	if err != nil {
+		return 0, err
+	}
+	d.rows = append(d.rows, row)
+	return 0, nil
+}
+
+// Row maps a record onto the configured columns, in column order.
+// Missing keys are written as NULL.
+func Row(m map[string]any, cols []Column) ([]any, error) {
+	row := make([]any, len(cols))
+	for i, col := range cols {
+		if col.From == TagsKey {
+			v, err := tagsJSON(m)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = v
+			continue
+		}
+		row[i] = m[col.From]
+	}
+	return row, nil
+}
+
+func tagsJSON(m map[string]any) (string, error) {
+	tags := make(map[string]any)
+	for k, v := range m {
+		if strings.HasPrefix(k, record.TagPrefix) {
+			tags[strings.TrimPrefix(k, record.TagPrefix)] = v
+		}
+	}
+	// encoding/json sorts map keys which keeps the value
+	// stable for use in an upsert key.
+	bs, err := json.Marshal(tags)
+	return string(bs), err
+}
+
+// QuoteIdentifier quotes each part of a (possibly schema qualified)
+// identifier using ANSI double quotes.
+func QuoteIdentifier(s string) string {
+	parts := strings.Split(s, ".")
+	for i, p := range parts {
+		parts[i] = `"` + strings.ReplaceAll(p, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// insertStatement renders an INSERT for the configured columns. With an
+// upsert key the statement uses ON CONFLICT, which is supported by
+// postgres, duckdb and sqlite.
+func insertStatement(conf config) string {
+	var cols, params []string
+	for i, col := range conf.Columns {
+		cols = append(cols, QuoteIdentifier(col.Name))
+		switch conf.Placeholder {
+		case PlaceholderDollar:
+			params = append(params, fmt.Sprintf("$%d", i+1))
+		default:
+			params = append(params, "?")
+		}
+	}
+
+	stmt := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		QuoteIdentifier(conf.Table),
+		strings.Join(cols, ", "),
+		strings.Join(params, ", "),
+	)
+
+	if len(conf.UpsertKey) == 0 {
+		return stmt
+	}
+
+	keys := make(map[string]struct{})
+	var keyCols []string
+	for _, k := range conf.UpsertKey {
+		keys[k] = struct{}{}
+		keyCols = append(keyCols, QuoteIdentifier(k))
+	}
+
+	var updates []string
+	for _, col := range conf.Columns {
+		if _, isKey := keys[col.Name]; isKey {
+			continue
+		}
+		q := QuoteIdentifier(col.Name)
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", q, q))
+	}
+	sort.Strings(updates)
+
+	if len(updates) == 0 {
+		return fmt.Sprintf("%s ON CONFLICT (%s) DO NOTHING", stmt, strings.Join(keyCols, ", "))
+	}
+
+	return fmt.Sprintf(
+		"%s ON CONFLICT (%s) DO UPDATE SET %s",
+		stmt,
+		strings.Join(keyCols, ", "),
+		strings.Join(updates, ", "),
+	)
+}
+
+func newDatabase(conf config, validate bool, t sink.Type, opts ...Option) (*Database, error) {
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	d := &Database{
+		config:   conf,
+		sinkType: t,
+		stmt:     insertStatement(conf),
+		logger:   zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	if d.db == nil && !validate {
+		db, err := sql.Open(conf.Driver, conf.URI)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := db.Ping(); err != nil {
+			return nil, err
+		}
+		d.db = db
+	}
+
+	return d, nil
+}
+
+// NewFromGenericConfig initializes a sink for any registered
+// database/sql driver.
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*Database, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	return newDatabase(conf, validate, sink.TypeSQL, opts...)
+}
+
+// NewPostgresFromGenericConfig is a preset of the database sink using
+// the postgres driver.
+func NewPostgresFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*Database, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+	conf.Driver = "postgres"
+
+	return newDatabase(conf, validate, sink.TypePostgres, opts...)
+}
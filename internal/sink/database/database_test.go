@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"testing"
+	"time"
+)
+
+var testColumns = []map[string]any{
+	{"name": "metric_name", "from": "name"},
+	{"name": "value"},
+	{"name": "window_start", "from": "window"},
+	{"name": "tags"},
+}
+
+func TestInsertStatement(t *testing.T) {
+	testCases := []struct {
+		name     string
+		conf     map[string]any
+		expected string
+	}{
+		{
+			name: "insert_postgres",
+			conf: map[string]any{
+				"driver":  "postgres",
+				"table":   "public.metrics",
+				"columns": testColumns,
+			},
+			expected: `INSERT INTO "public"."metrics" ("metric_name", "value", "window_start", "tags") VALUES ($1, $2, $3, $4)`,
+		},
+		{
+			name: "upsert",
+			conf: map[string]any{
+				"driver":     "duckdb",
+				"table":      "metrics",
+				"columns":    testColumns,
+				"upsert_key": []string{"metric_name", "window_start", "tags"},
+			},
+			expected: `INSERT INTO "metrics" ("metric_name", "value", "window_start", "tags") VALUES (?, ?, ?, ?) ON CONFLICT ("metric_name", "window_start", "tags") DO UPDATE SET "value" = EXCLUDED."value"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewFromGenericConfig(tc.conf, true)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, d.stmt)
+		})
+	}
+}
+
+func TestNewFromGenericConfig_UnknownUpsertColumn(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"driver":     "duckdb",
+		"table":      "metrics",
+		"columns":    testColumns,
+		"upsert_key": []string{"customer"},
+	}, true)
+	assert.EqualError(t, err, "database sink upsert_key column: \"customer\" is not a configured column")
+}
+
+func TestDatabase_Flush_UpsertReplacesRows(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	assert.NoError(t, err)
+
+	_, err = db.Exec(`
+CREATE TABLE metrics (
+	metric_name VARCHAR,
+	value DOUBLE,
+	window_start TIMESTAMP,
+	tags VARCHAR,
+	PRIMARY KEY (metric_name, window_start, tags)
+)`)
+	assert.NoError(t, err)
+
+	d, err := NewFromGenericConfig(
+		map[string]any{
+			"driver":     "duckdb",
+			"table":      "metrics",
+			"columns":    testColumns,
+			"upsert_key": []string{"metric_name", "window_start", "tags"},
+		},
+		false,
+		WithDB(db),
+	)
+	assert.NoError(t, err)
+	defer d.Close()
+
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// invoke the same window twice, the second value should replace the first
+	for _, v := range []float64{1, 2} {
+		_, err = d.Write(ctx, &metric.Metric{
+			Name:   "core.users.total",
+			Value:  v,
+			Window: &window,
+			Tags: map[string]string{
+				"customer": "google",
+				"env":      "prod",
+			},
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, d.Flush(ctx))
+	}
+
+	var count int
+	var value float64
+	var tags string
+	err = db.QueryRow(`SELECT COUNT(*), MAX(value), MAX(tags) FROM metrics`).Scan(&count, &value, &tags)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, float64(2), value)
+	assert.Equal(t, `{"customer":"google","env":"prod"}`, tags)
+}
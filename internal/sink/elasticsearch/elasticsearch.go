@@ -0,0 +1,279 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/partition"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxReportedErrors limits how many item errors are included in the
+// error returned from Flush. All item errors are logged.
+const maxReportedErrors = 3
+
+type config struct {
+	URI string
+	// Index may be templated with the record window, ie:
+	// latte-{{.Year}}.{{printf "%02d" .Month}}
+	Index     string
+	BatchSize int `mapstructure:"batch_size"`
+	Username  string
+	Password  string
+	APIKey    string `mapstructure:"api_key"`
+	Headers   map[string]string
+}
+
+type action struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type bulkItem struct {
+	ID     string `json:"_id"`
+	Index  string `json:"_index"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+type bulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+type Option func(*Elasticsearch)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(e *Elasticsearch) {
+		e.logger = l
+	}
+}
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(e *Elasticsearch) {
+		e.client = c
+	}
+}
+
+// Elasticsearch indexes records using the `_bulk` API. Documents ids
+// are derived from the record name, window and tags so re-collecting
+// a window overwrites the previous documents.
+type Elasticsearch struct {
+	client *http.Client
+	config config
+	// lines are the buffered action and document pairs
+	lines [][]byte
+	index *partition.Partitioner
+
+	logger *zap.Logger
+}
+
+func (e *Elasticsearch) Close() error {
+	return nil
+}
+
+func (e *Elasticsearch) Flush(ctx context.Context) error {
+	defer func() {
+		e.lines = nil
+	}()
+
+	batchSize := e.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(e.lines) / 2
+	}
+
+	for start := 0; start < len(e.lines); start += batchSize * 2 {
+		end := start + batchSize*2
+		if end > len(e.lines) {
+			end = len(e.lines)
+		}
+		if err := e.bulk(ctx, e.lines[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Elasticsearch) Type() sink.Type {
+	return sink.TypeElasticsearch
+}
+
+func (e *Elasticsearch) Write(ctx context.Context, r record.Record) (int, error) {
+	m := r.Map()
+
+	t, ok := record.Time(m)
+	if !ok {
+		t = time.Now().UTC()
+	}
+
+	index, err := e.index.Render(t)
+	if err != nil {
+		return 0, err
+	}
+
+	a, err := json.Marshal(map[string]action{
+		"index": {
+			Index: index,
+			ID:    record.Key(m),
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	doc, err := json.Marshal(m)
+	if err != nil {
+		return 0, retry.Permanent(err)
+	}
+
+	e.lines = append(e.lines, a, doc)
+	return len(doc), nil
+}
+
+func (e *Elasticsearch) bulk(ctx context.Context, lines [][]byte) error {
+	var body bytes.Buffer
+	for _, l := range lines {
+		body.Write(l)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		strings.TrimSuffix(e.config.URI, "/")+"/_bulk",
+		&body,
+	)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range e.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if e.config.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.config.APIKey)
+	} else if e.config.Username != "" {
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("elasticsearch bulk request failed, status: %d, body: %q", resp.StatusCode, bs)
+	}
+
+	var br bulkResponse
+	if err := json.Unmarshal(bs, &br); err != nil {
+		return err
+	}
+
+	e.logger.Debug(
+		"sinks.Elasticsearch.bulk",
+		zap.Int("items", len(br.Items)),
+		zap.Bool("errors", br.Errors),
+	)
+
+	if !br.Errors {
+		return nil
+	}
+
+	return e.itemErrors(br)
+}
+
+// itemErrors logs every failed bulk item and returns an error
+// summarizing the failures.
+func (e *Elasticsearch) itemErrors(br bulkResponse) error {
+	var failed int
+	var reasons []string
+
+	for _, item := range br.Items {
+		for op, result := range item {
+			if result.Error == nil {
+				continue
+			}
+			failed++
+
+			e.logger.Error(
+				"sinks.Elasticsearch.bulk",
+				zap.String("msg", "item failed"),
+				zap.String("op", op),
+				zap.String("index", result.Index),
+				zap.String("id", result.ID),
+				zap.Int("status", result.Status),
+				zap.String("error.type", result.Error.Type),
+				zap.String("error.reason", result.Error.Reason),
+			)
+
+			if len(reasons) < maxReportedErrors {
+				reasons = append(reasons, fmt.Sprintf(
+					"%s: %s: %s",
+					result.ID,
+					result.Error.Type,
+					result.Error.Reason,
+				))
+			}
+		}
+	}
+
+	return fmt.Errorf(
+		"elasticsearch bulk request: %d of %d items failed: %s",
+		failed,
+		len(br.Items),
+		strings.Join(reasons, "; "),
+	)
+}
+
+func NewFromGenericConfig(m map[string]any, opts ...Option) (*Elasticsearch, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if conf.URI == "" {
+		return nil, fmt.Errorf("elasticsearch sink requires a %q", "uri")
+	}
+
+	if conf.Index == "" {
+		return nil, fmt.Errorf("elasticsearch sink requires an %q", "index")
+	}
+
+	p, err := partition.New(conf.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Elasticsearch{
+		client: &http.Client{},
+		config: conf,
+		index:  p,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+}
@@ -0,0 +1,113 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/record"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type bulkRequest struct {
+	actions []map[string]action
+	docs    []map[string]any
+}
+
+func newBulkServer(t *testing.T, resp string) (*httptest.Server, *[]bulkRequest) {
+	var reqs []bulkRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+
+		var br bulkRequest
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 == 0 {
+				var a map[string]action
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), &a))
+				br.actions = append(br.actions, a)
+			} else {
+				var d map[string]any
+				assert.NoError(t, json.Unmarshal(scanner.Bytes(), &d))
+				br.docs = append(br.docs, d)
+			}
+		}
+		reqs = append(reqs, br)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &reqs
+}
+
+func TestElasticsearch_Flush_Batches(t *testing.T) {
+	ts, reqs := newBulkServer(t, `{"errors":false,"items":[]}`)
+
+	e, err := NewFromGenericConfig(map[string]any{
+		"uri":        ts.URL,
+		"index":      `latte-{{.Year}}.{{printf "%02d" .Month}}`,
+		"batch_size": 2,
+	}, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	window := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var ms []*metric.Metric
+	for _, customer := range []string{"amazon", "google", "microsoft"} {
+		ms = append(ms, &metric.Metric{
+			Name:   "core.users.total",
+			Value:  1,
+			Window: &window,
+			Tags:   map[string]string{"customer": customer},
+		})
+	}
+
+	for _, m := range ms {
+		_, err := e.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, e.Flush(context.Background()))
+
+	assert.Equal(t, 2, len(*reqs))
+	assert.Equal(t, 2, len((*reqs)[0].actions))
+	assert.Equal(t, 1, len((*reqs)[1].actions))
+
+	a := (*reqs)[0].actions[0]["index"]
+	assert.Equal(t, "latte-2024.03", a.Index)
+	assert.Equal(t, record.Key(ms[0].Map()), a.ID)
+	assert.Equal(t, "core.users.total", (*reqs)[0].docs[0]["name"])
+
+	// buffer is reset after each flush
+	assert.NoError(t, e.Flush(context.Background()))
+	assert.Equal(t, 2, len(*reqs))
+}
+
+func TestElasticsearch_Flush_ItemErrors(t *testing.T) {
+	ts, _ := newBulkServer(t, `{
+  "errors": true,
+  "items": [
+    {"index": {"_index": "latte", "_id": "a", "status": 201}},
+    {"index": {"_index": "latte", "_id": "b", "status": 400, "error": {"type": "mapper_parsing_exception", "reason": "failed to parse field [value]"}}}
+  ]
+}`)
+
+	e, err := NewFromGenericConfig(map[string]any{
+		"uri":   ts.URL,
+		"index": "latte",
+	}, WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := e.Write(context.Background(), &metric.Metric{Name: "core.users.total"})
+		assert.NoError(t, err)
+	}
+
+	err = e.Flush(context.Background())
+	assert.EqualError(t, err, "elasticsearch bulk request: 1 of 2 items failed: b: mapper_parsing_exception: failed to parse field [value]")
+}
@@ -0,0 +1,166 @@
+package sink
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Fields reshapes records before they are written. Fields are applied
+// in order: include, exclude, nest then rename.
+type Fields struct {
+	// Include and Exclude are glob patterns of record keys, ie: tag.*
+	Include []string
+	Exclude []string
+	// Nest moves all keys with a prefix into a nested map, ie:
+	// `tags: tag.` nests `tag.customer` as `tags.customer`.
+	Nest map[string]string
+	// Rename maps a key to its new name.
+	Rename map[string]string
+}
+
+func (f Fields) Validate() error {
+	for _, p := range append(f.Include, f.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("fields pattern: %q: %w", p, err)
+		}
+	}
+
+	for k, prefix := range f.Nest {
+		if prefix == "" {
+			return fmt.Errorf("fields nest: %q requires a prefix", k)
+		}
+	}
+	return nil
+}
+
+func (f Fields) Apply(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if len(f.Include) > 0 && !matchAny(f.Include, k) {
+			continue
+		}
+		if matchAny(f.Exclude, k) {
+			continue
+		}
+		out[k] = v
+	}
+
+	// nest and rename in key order so overlapping prefixes and chained
+	// renames resolve the same way on every record.
+	for _, target := range sortedKeys(f.Nest) {
+		prefix := f.Nest[target]
+		nested := make(map[string]any)
+		for k, v := range out {
+			if strings.HasPrefix(k, prefix) {
+				nested[strings.TrimPrefix(k, prefix)] = v
+				delete(out, k)
+			}
+		}
+		out[target] = nested
+	}
+
+	for _, from := range sortedKeys(f.Rename) {
+		to := f.Rename[from]
+		if v, ok := out[from]; ok {
+			delete(out, from)
+			out[to] = v
+		}
+	}
+
+	return out
+}
+
+// Preserves reports whether key k is written unchanged, ie: it is not
+// excluded, nested, renamed or overwritten.
+func (f Fields) Preserves(k string) bool {
+	for _, to := range f.Rename {
+		if to == k {
+			return false
+		}
+	}
+
+	v := new(int)
+	return f.Apply(map[string]any{k: v})[k] == v
+}
+
+// Writes reports whether key k is created by nesting or renaming.
+func (f Fields) Writes(k string) bool {
+	if _, ok := f.Nest[k]; ok {
+		return true
+	}
+	for _, to := range f.Rename {
+		if to == k {
+			return true
+		}
+	}
+	return false
+}
+
+// PreservesPrefix reports whether every key with prefix is written
+// unchanged. Patterns which might match such a key are assumed to.
+func (f Fields) PreservesPrefix(prefix string) bool {
+	if len(f.Include) > 0 {
+		all := false
+		for _, p := range f.Include {
+			if p == "*" || p == prefix+"*" {
+				all = true
+			}
+		}
+		if !all {
+			return false
+		}
+	}
+
+	for _, p := range f.Exclude {
+		if overlaps(literal(p), prefix) {
+			return false
+		}
+	}
+
+	for target, p := range f.Nest {
+		if overlaps(p, prefix) || strings.HasPrefix(target, prefix) {
+			return false
+		}
+	}
+
+	for from, to := range f.Rename {
+		if strings.HasPrefix(from, prefix) || strings.HasPrefix(to, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// literal returns the part of a pattern before its first meta
+// character.
+func literal(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// overlaps reports whether keys starting with a and b may coincide.
+func overlaps(a, b string) bool {
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func matchAny(patterns []string, k string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, k); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package fields
+
+import (
+	"context"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+)
+
+type mapped map[string]any
+
+func (m mapped) Map() map[string]any {
+	return m
+}
+
+// Sinker reshapes each record before writing it to the wrapped sinker.
+type Sinker struct {
+	invoker.Sinker
+
+	fields sink.Fields
+}
+
+func (s *Sinker) Write(ctx context.Context, r record.Record) (int, error) {
+	return s.Sinker.Write(ctx, mapped(s.fields.Apply(r.Map())))
+}
+
+func (s *Sinker) Ping(ctx context.Context) error {
+	return invoker.Ping(ctx, s.Sinker)
+}
+
+func New(s invoker.Sinker, f sink.Fields) *Sinker {
+	return &Sinker{
+		Sinker: s,
+		fields: f,
+	}
+}
@@ -0,0 +1,234 @@
+package sink
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFields_Apply(t *testing.T) {
+	m := map[string]any{
+		"uuid":         "1",
+		"name":         "core.users.total",
+		"value":        1.0,
+		"window":       nil,
+		"tag.customer": "google",
+		"tag.env":      "prod",
+	}
+
+	testCases := []struct {
+		name     string
+		fields   Fields
+		expected map[string]any
+	}{
+		{
+			name: "include",
+			fields: Fields{
+				Include: []string{"name", "tag.*"},
+			},
+			expected: map[string]any{
+				"name":         "core.users.total",
+				"tag.customer": "google",
+				"tag.env":      "prod",
+			},
+		},
+		{
+			name: "exclude_nest_rename",
+			fields: Fields{
+				Exclude: []string{"uuid", "window", "tag.env"},
+				Nest:    map[string]string{"labels": "tag."},
+				Rename:  map[string]string{"name": "metric", "labels": "dimensions"},
+			},
+			expected: map[string]any{
+				"metric": "core.users.total",
+				"value":  1.0,
+				"dimensions": map[string]any{
+					"customer": "google",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.fields.Apply(m))
+		})
+	}
+
+	// the record is left untouched
+	assert.Equal(t, 6, len(m))
+}
+
+func TestFields_Validate(t *testing.T) {
+	err := Fields{Nest: map[string]string{"tags": ""}}.Validate()
+	assert.EqualError(t, err, `fields nest: "tags" requires a prefix`)
+}
+
+func TestFields_Apply_NestOrder(t *testing.T) {
+	f := Fields{
+		Nest: map[string]string{
+			"b": "tag.c",
+			"a": "tag.",
+		},
+	}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, map[string]any{
+			"a": map[string]any{
+				"customer": "google",
+				"env":      "prod",
+			},
+			"b": map[string]any{},
+		}, f.Apply(map[string]any{
+			"tag.customer": "google",
+			"tag.env":      "prod",
+		}))
+	}
+}
+
+func TestConfig_Validate_Fields(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config Config
+		err    string
+	}{
+		{
+			name: "otlp",
+			config: Config{
+				Type:   TypeOTLP,
+				Fields: &Fields{Exclude: []string{"uuid"}},
+			},
+			err: `fields are not supported by the "otlp" sink`,
+		},
+		{
+			name: "statsd",
+			config: Config{
+				Type:   TypeStatsD,
+				Fields: &Fields{Exclude: []string{"uuid"}},
+			},
+			err: `fields are not supported by the "statsd" sink`,
+		},
+		{
+			name: "prometheus_exclude_value",
+			config: Config{
+				Type:   TypePrometheus,
+				Fields: &Fields{Exclude: []string{"v*"}},
+			},
+			err: `fields: "prometheus" sink requires record key: "value"`,
+		},
+		{
+			name: "prometheus_rename_value",
+			config: Config{
+				Type:   TypePrometheus,
+				Fields: &Fields{Rename: map[string]string{"value": "count"}},
+			},
+			err: `fields: "prometheus" sink requires record key: "value"`,
+		},
+		{
+			name: "prometheus_include_without_name",
+			config: Config{
+				Type:   TypePrometheus,
+				Fields: &Fields{Include: []string{"value", "tag.*"}},
+			},
+			err: `fields: "prometheus" sink requires record key: "name"`,
+		},
+		{
+			name: "prometheus_exclude_tags",
+			config: Config{
+				Type:   TypePrometheus,
+				Fields: &Fields{Exclude: []string{"tag.env"}},
+			},
+		},
+		{
+			name: "elasticsearch_nest_tags",
+			config: Config{
+				Type:   TypeElasticsearch,
+				Fields: &Fields{Nest: map[string]string{"labels": "tag."}},
+			},
+			err: `fields: "elasticsearch" sink requires record keys: "tag.*"`,
+		},
+		{
+			name: "warehouse_exclude_tag",
+			config: Config{
+				Type:   TypeWarehouse,
+				Fields: &Fields{Exclude: []string{"tag.env"}},
+			},
+			err: `fields: "warehouse" sink requires record keys: "tag.*"`,
+		},
+		{
+			name: "postgres_include_without_tags",
+			config: Config{
+				Type:   TypePostgres,
+				Fields: &Fields{Include: []string{"name", "window", "timestamp", "value"}},
+			},
+			err: `fields: "postgres" sink requires record keys: "tag.*"`,
+		},
+		{
+			name: "sql_rename_window",
+			config: Config{
+				Type:   TypeSQL,
+				Fields: &Fields{Rename: map[string]string{"window": "period"}},
+			},
+			err: `fields: "sql" sink requires record key: "window"`,
+		},
+		{
+			name: "mongodb_nest_into_tags",
+			config: Config{
+				Type:   TypeMongoDB,
+				Fields: &Fields{Nest: map[string]string{"tags": "meta."}},
+			},
+			err: `fields: "mongodb" sink reserves record key: "tags"`,
+		},
+		{
+			name: "mongodb_exclude_uuid",
+			config: Config{
+				Type: TypeMongoDB,
+				Fields: &Fields{
+					Include: []string{"name", "window", "timestamp", "value", "tag.*"},
+					Exclude: []string{"uuid"},
+				},
+			},
+		},
+		{
+			name: "console",
+			config: Config{
+				Type:   TypeConsole,
+				Fields: &Fields{Rename: map[string]string{"value": "count"}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestFields_PreservesPrefix(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fields   Fields
+		expected bool
+	}{
+		{name: "empty", fields: Fields{}, expected: true},
+		{name: "include_all", fields: Fields{Include: []string{"name", "tag.*"}}, expected: true},
+		{name: "include_some", fields: Fields{Include: []string{"tag.env"}}, expected: false},
+		{name: "exclude_other", fields: Fields{Exclude: []string{"uuid", "w*"}}, expected: true},
+		{name: "exclude_glob", fields: Fields{Exclude: []string{"t*"}}, expected: false},
+		{name: "nest_other", fields: Fields{Nest: map[string]string{"meta": "meta."}}, expected: true},
+		{name: "nest_into_prefix", fields: Fields{Nest: map[string]string{"tag.all": "meta."}}, expected: false},
+		{name: "rename_from", fields: Fields{Rename: map[string]string{"tag.env": "env"}}, expected: false},
+		{name: "rename_to", fields: Fields{Rename: map[string]string{"env": "tag.env"}}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.fields.PreservesPrefix("tag."))
+		})
+	}
+}
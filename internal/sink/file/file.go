@@ -3,34 +3,82 @@ package file
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/audit"
 	"github.com/turbolytics/latte/internal/encoding"
+	"github.com/turbolytics/latte/internal/partition"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 type config struct {
+	// Audit writes each record as an entry of a hash chain, which
+	// can be checked with `latte audit verify`.
+	Audit    bool
 	Encoding encoding.Config
+	// Path may be templated with the window being collected, ie:
+	// /var/lib/latte/year={{.Year}}/month={{.Month}}/day={{.Day}}/metrics.log
 	Path     string
+	Rotation *RotationConfig
+	// Fsync syncs the file to disk on every Flush.
+	Fsync bool
+}
+
+type Option func(*File)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(f *File) {
+		f.logger = l
+	}
+}
+
+func WithNow(now func() time.Time) Option {
+	return func(f *File) {
+		f.now = now
+	}
 }
 
 type File struct {
-	config  config
-	encoder encoding.Encoder
-	f       *os.File
+	chain       audit.Chain
+	config      config
+	encoder     encoding.Encoder
+	f           *os.File
+	partitioner *partition.Partitioner
+
+	// path of the currently open file
+	path string
+	// opened is when the currently open file was opened
+	opened time.Time
+	size   int64
+
+	logger *zap.Logger
+	now    func() time.Time
 }
 
 func (fs *File) Close() error {
+	if fs.f == nil {
+		return nil
+	}
 	return fs.f.Close()
 }
 
 func (fs *File) Flush(ctx context.Context) error {
-	return nil
+	if fs.f == nil || !fs.config.Fsync {
+		return nil
+	}
+	return fs.f.Sync()
 }
 
 func (fs *File) Type() sink.Type {
-	return sink.TypeHTTP
+	return sink.TypeFile
 }
 
 func (fs *File) Write(ctx context.Context, r record.Record) (int, error) {
@@ -40,42 +88,260 @@ func (fs *File) Write(ctx context.Context, r record.Record) (int, error) {
 	}
 
 	if err := fs.encoder.Write(r.Map()); err != nil {
-		return 0, err
+		return 0, retry.Permanent(err)
 	}
 
 	bs := buf.Bytes()
+	if err := fs.prepare(ctx, int64(len(bs)+1)); err != nil {
+		return 0, err
+	}
+
+	if fs.config.Audit {
+		return fs.appendEntry(bs)
+	}
+
 	n, err := fs.f.Write(bs)
+	fs.size += int64(n)
 	if err != nil {
 		return n, err
 	}
 	_, err = fs.f.Write([]byte("\n"))
+	fs.size++
 	return n, err
 }
 
-func NewFromGenericConfig(m map[string]any, validate bool) (*File, error) {
+// appendEntry appends bs as the next entry of the audit chain. The
+// file is locked while appending so sinks sharing an audit log, in
+// this or another process, extend a single chain.
+func (fs *File) appendEntry(bs []byte) (int, error) {
+	if err := lock(fs.f); err != nil {
+		return 0, err
+	}
+	defer unlock(fs.f)
+
+	if err := fs.syncChain(); err != nil {
+		return 0, err
+	}
+
+	prev := fs.chain
+	entry, err := fs.chain.Next(bs)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := fs.f.Write(append(entry, '\n'))
+	fs.size += int64(n)
+	if err != nil {
+		// the entry was not persisted, keep the chain pointing
+		// at the last written entry
+		fs.chain = prev
+		return n, err
+	}
+	return n - 1, nil
+}
+
+// syncChain resumes the chain from entries appended by other writers
+// since the last write of this sink.
+func (fs *File) syncChain() error {
+	info, err := fs.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == fs.size {
+		return nil
+	}
+
+	f, err := os.Open(fs.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// entries are whole lines, so reading from the previous size only
+	// reads the entries appended since.
+	from := fs.size
+	if info.Size() < from {
+		from = 0
+	}
+
+	e, err := audit.LastEntry(io.NewSectionReader(f, from, info.Size()-from))
+	if err != nil {
+		return fmt.Errorf("unable to resume audit log %q: %w", fs.path, err)
+	}
+	if e != nil {
+		fs.chain.Resume(*e)
+	}
+	fs.size = info.Size()
+	return nil
+}
+
+// prepare makes sure the file for the record's window is open, and
+// rotates it when writing n more bytes would pass the rotation limits.
+func (fs *File) prepare(ctx context.Context, n int64) error {
+	p, err := fs.render(ctx)
+	if err != nil {
+		return err
+	}
+
+	if fs.f != nil && p != fs.path {
+		if err := fs.f.Close(); err != nil {
+			return err
+		}
+		fs.f = nil
+	}
+
+	if fs.f != nil && fs.config.Rotation.shouldRotate(fs.size, n, fs.now().Sub(fs.opened)) {
+		if err := fs.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if fs.f == nil {
+		return fs.open(p)
+	}
+	return nil
+}
+
+func (fs *File) render(ctx context.Context) (string, error) {
+	t, ok := ctx.Value("window.start").(time.Time)
+	if !ok {
+		t = fs.now()
+	}
+	return fs.partitioner.Render(t)
+}
+
+func (fs *File) open(p string) error {
+	if dir := filepath.Dir(p); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	if fs.config.Audit {
+		if err := fs.resumeChain(p); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	fs.f = f
+	fs.path = p
+	fs.size = info.Size()
+	fs.opened = fs.now()
+	return nil
+}
+
+// resumeChain continues the audit chain from the last entry of an
+// existing file. A new (or empty) file continues the chain of the
+// previously open file.
+func (fs *File) resumeChain(p string) error {
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	e, err := audit.LastEntry(f)
+	if err != nil {
+		return fmt.Errorf("unable to resume audit log %q: %w", p, err)
+	}
+	if e != nil {
+		fs.chain.Resume(*e)
+	}
+	return nil
+}
+
+// rotate closes the current file, moves it to a timestamped backup
+// and applies retention to the existing backups.
+func (fs *File) rotate() error {
+	if err := fs.f.Close(); err != nil {
+		return err
+	}
+	fs.f = nil
+
+	backup := backupPath(fs.path, fs.now())
+	fs.logger.Debug(
+		"sinks.File.rotate",
+		zap.String("path", fs.path),
+		zap.String("backup", backup),
+	)
+
+	if err := os.Rename(fs.path, backup); err != nil {
+		return err
+	}
+
+	return fs.config.Rotation.applyRetention(fs.path, fs.now())
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*File, error) {
 	var conf config
 	if err := mapstructure.Decode(m, &conf); err != nil {
 		return nil, err
 	}
 
-	var f *os.File
-	var err error
-	if !validate {
-		f, err = os.OpenFile(conf.Path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
-		if err != nil {
+	if conf.Path == "" {
+		return nil, fmt.Errorf("file sink requires a %q", "path")
+	}
+
+	if conf.Audit {
+		switch conf.Encoding.Type {
+		case "", encoding.TypeJSON:
+		default:
+			return nil, fmt.Errorf("file sink audit mode requires json encoding, received: %q", conf.Encoding.Type)
+		}
+	}
+
+	if conf.Rotation != nil {
+		if err := conf.Rotation.init(); err != nil {
 			return nil, err
 		}
 	}
 
+	p, err := partition.New(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+
 	e, err := encoding.NewEncoder(conf.Encoding)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return &File{
-		config:  conf,
-		encoder: e,
-		f:       f,
-	}, nil
+	fs := &File{
+		config:      conf,
+		encoder:     e,
+		partitioner: p,
+		logger:      zap.NewNop(),
+		now: func() time.Time {
+			return time.Now().UTC()
+		},
+	}
+
+	for _, opt := range opts {
+		opt(fs)
+	}
+
+	// a static path is opened immediately so a misconfigured
+	// path is surfaced on startup.
+	if !validate && !strings.Contains(conf.Path, "{{") {
+		if err := fs.open(conf.Path); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
 }
@@ -0,0 +1,180 @@
+package file
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/audit"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/sink"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile_Write_PartitionedPath(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFromGenericConfig(map[string]any{
+		"path": path.Join(dir, "year={{.Year}}/month={{.Month}}/day={{.Day}}/hour={{.Hour}}/metrics.log"),
+	}, false)
+	assert.NoError(t, err)
+	defer fs.Close()
+	assert.Equal(t, sink.TypeFile, fs.Type())
+
+	for _, h := range []int{1, 2} {
+		start := time.Date(2024, 3, 1, h, 0, 0, 0, time.UTC)
+		ctx := context.WithValue(context.Background(), "window.start", start)
+		_, err := fs.Write(ctx, &metric.Metric{Name: "core.users.total"})
+		assert.NoError(t, err)
+	}
+
+	for _, h := range []string{"1", "2"} {
+		p := path.Join(dir, "year=2024/month=3/day=1/hour="+h+"/metrics.log")
+		bs, err := os.ReadFile(p)
+		assert.NoError(t, err)
+		assert.Contains(t, string(bs), `"name":"core.users.total"`)
+	}
+}
+
+func TestFile_Write_RotatesBySizeWithRetention(t *testing.T) {
+	dir := t.TempDir()
+	p := path.Join(dir, "latte.log")
+
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	fs, err := NewFromGenericConfig(map[string]any{
+		"path": p,
+		"rotation": map[string]any{
+			"max_bytes":   1,
+			"max_backups": 2,
+		},
+		"fsync": true,
+	}, false, WithNow(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}))
+	assert.NoError(t, err)
+	defer fs.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, err := fs.Write(ctx, &metric.Metric{Name: "core.users.total"})
+		assert.NoError(t, err)
+		assert.NoError(t, fs.Flush(ctx))
+	}
+
+	bs, err := backups(p)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(bs))
+
+	matches, err := filepath.Glob(path.Join(dir, "*"))
+	assert.NoError(t, err)
+	// current file + 2 retained backups
+	assert.Equal(t, 3, len(matches))
+}
+
+func TestRotationConfig_shouldRotate(t *testing.T) {
+	rc := &RotationConfig{MaxBytes: 10, MaxAge: "1h"}
+	assert.NoError(t, rc.init())
+
+	assert.False(t, rc.shouldRotate(0, 100, 0))
+	assert.False(t, rc.shouldRotate(5, 5, 0))
+	assert.True(t, rc.shouldRotate(5, 6, 0))
+	assert.True(t, rc.shouldRotate(1, 1, time.Hour))
+
+	var disabled *RotationConfig
+	assert.False(t, disabled.shouldRotate(100, 100, time.Hour))
+}
+
+func TestRotationConfig_applyRetention_MaxBackupAge(t *testing.T) {
+	dir := t.TempDir()
+	p := path.Join(dir, "latte.log")
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	old := backupPath(p, now.Add(-48*time.Hour))
+	recent := backupPath(p, now.Add(-time.Hour))
+	for _, b := range []string{old, recent} {
+		assert.NoError(t, os.WriteFile(b, []byte("{}"), 0644))
+	}
+
+	rc := &RotationConfig{MaxBackupAge: "24h"}
+	assert.NoError(t, rc.init())
+	assert.NoError(t, rc.applyRetention(p, now))
+
+	bs, err := backups(p)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{recent}, bs)
+}
+
+func TestFile_Write_AuditResumesChain(t *testing.T) {
+	p := path.Join(t.TempDir(), "latte.audit.log")
+
+	// each sink simulates a separate process writing to the same log
+	for i := 0; i < 2; i++ {
+		fs, err := NewFromGenericConfig(map[string]any{
+			"path":  p,
+			"audit": true,
+		}, false)
+		assert.NoError(t, err)
+
+		for j := 0; j < 2; j++ {
+			_, err := fs.Write(context.Background(), &metric.Metric{Name: "core.users.total"})
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, fs.Close())
+	}
+
+	f, err := os.Open(p)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	v := &audit.Verifier{}
+	assert.NoError(t, v.Verify(p, f))
+	assert.Empty(t, v.Violations())
+	assert.Equal(t, uint64(4), v.LastSeq())
+}
+
+func TestFile_Write_AuditSharedLog(t *testing.T) {
+	p := path.Join(t.TempDir(), "latte.audit.log")
+
+	// both sinks are open at once, ie: collectors sharing a log
+	var sinks []*File
+	for i := 0; i < 2; i++ {
+		fs, err := NewFromGenericConfig(map[string]any{
+			"path":  p,
+			"audit": true,
+		}, false)
+		assert.NoError(t, err)
+		sinks = append(sinks, fs)
+	}
+
+	for j := 0; j < 3; j++ {
+		for _, fs := range sinks {
+			_, err := fs.Write(context.Background(), &metric.Metric{Name: "core.users.total"})
+			assert.NoError(t, err)
+		}
+	}
+	for _, fs := range sinks {
+		assert.NoError(t, fs.Close())
+	}
+
+	f, err := os.Open(p)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	v := &audit.Verifier{}
+	assert.NoError(t, v.Verify(p, f))
+	assert.Empty(t, v.Violations())
+	assert.Equal(t, uint64(6), v.LastSeq())
+}
+
+func TestNewFromGenericConfig_AuditRequiresJSON(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"path":  "/tmp/latte.audit.log",
+		"audit": true,
+		"encoding": map[string]any{
+			"type": "influx",
+		},
+	}, true)
+	assert.EqualError(t, err, "file sink audit mode requires json encoding, received: \"influx\"")
+}
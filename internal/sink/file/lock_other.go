@@ -0,0 +1,17 @@
+//go:build !unix
+
+package file
+
+import (
+	"os"
+)
+
+// audit logs are not locked on platforms without flock, a log must
+// only be written by a single sink.
+func lock(f *os.File) error {
+	return nil
+}
+
+func unlock(f *os.File) error {
+	return nil
+}
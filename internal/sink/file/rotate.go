@@ -0,0 +1,130 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000000000"
+
+type RotationConfig struct {
+	// MaxBytes rotates the file before it grows larger than MaxBytes.
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// MaxAge rotates the file once it has been open for longer than MaxAge.
+	MaxAge string `mapstructure:"max_age"`
+	// MaxBackups is the number of rotated files to retain.
+	MaxBackups int `mapstructure:"max_backups"`
+	// MaxBackupAge removes rotated files older than MaxBackupAge.
+	MaxBackupAge string `mapstructure:"max_backup_age"`
+
+	// mapstructure does not parse to native go types
+	maxAge       time.Duration
+	maxBackupAge time.Duration
+}
+
+func (rc *RotationConfig) init() error {
+	if rc.MaxAge != "" {
+		d, err := time.ParseDuration(rc.MaxAge)
+		if err != nil {
+			return err
+		}
+		rc.maxAge = d
+	}
+
+	if rc.MaxBackupAge != "" {
+		d, err := time.ParseDuration(rc.MaxBackupAge)
+		if err != nil {
+			return err
+		}
+		rc.maxBackupAge = d
+	}
+
+	if rc.MaxBytes < 0 || rc.MaxBackups < 0 {
+		return fmt.Errorf("file rotation limits must not be negative")
+	}
+
+	return nil
+}
+
+func (rc *RotationConfig) shouldRotate(size int64, n int64, age time.Duration) bool {
+	if rc == nil {
+		return false
+	}
+
+	// an empty file is never rotated, even if a single record
+	// is larger than the limit
+	if rc.MaxBytes > 0 && size > 0 && size+n > rc.MaxBytes {
+		return true
+	}
+
+	if rc.maxAge > 0 && age >= rc.maxAge {
+		return true
+	}
+
+	return false
+}
+
+// applyRetention removes the oldest backups of path beyond MaxBackups
+// and any backups older than MaxBackupAge.
+func (rc *RotationConfig) applyRetention(path string, now time.Time) error {
+	backups, err := backups(path)
+	if err != nil {
+		return err
+	}
+
+	var remove []string
+	if rc.MaxBackups > 0 && len(backups) > rc.MaxBackups {
+		remove = append(remove, backups[:len(backups)-rc.MaxBackups]...)
+		backups = backups[len(backups)-rc.MaxBackups:]
+	}
+
+	if rc.maxBackupAge > 0 {
+		for _, b := range backups {
+			t, err := backupTime(path, b)
+			if err != nil {
+				continue
+			}
+			if now.Sub(t) > rc.maxBackupAge {
+				remove = append(remove, b)
+			}
+		}
+	}
+
+	for _, b := range remove {
+		if err := os.Remove(b); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func backupPath(path string, t time.Time) string {
+	return fmt.Sprintf("%s.%s", path, t.UTC().Format(backupTimeFormat))
+}
+
+func backupTime(path string, backup string) (time.Time, error) {
+	return time.Parse(backupTimeFormat, strings.TrimPrefix(backup, path+"."))
+}
+
+// backups lists the rotated files of path, oldest first.
+func backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+
+	var bs []string
+	for _, m := range matches {
+		if _, err := backupTime(path, m); err == nil {
+			bs = append(bs, m)
+		}
+	}
+
+	// backup names sort chronologically
+	sort.Strings(bs)
+	return bs, nil
+}
@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"fmt"
+	"github.com/turbolytics/latte/internal/record"
+	"path"
+)
+
+type Range struct {
+	Min *float64
+	Max *float64
+}
+
+func (r Range) Validate() error {
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		return fmt.Errorf("filter value min: %v is greater than max: %v", *r.Min, *r.Max)
+	}
+	return nil
+}
+
+// Match checks if v is within the inclusive range.
+func (r Range) Match(v float64) bool {
+	if r.Min != nil && v < *r.Min {
+		return false
+	}
+	if r.Max != nil && v > *r.Max {
+		return false
+	}
+	return true
+}
+
+// Filter selects the records a sink receives. Every configured rule
+// must match.
+type Filter struct {
+	// Names are glob patterns, ie: core.users.*
+	Names []string
+	// Tags maps a tag key to its allowed values.
+	Tags  map[string][]string
+	Value *Range
+}
+
+func (f Filter) Validate() error {
+	for _, n := range f.Names {
+		if _, err := path.Match(n, ""); err != nil {
+			return fmt.Errorf("filter name: %q: %w", n, err)
+		}
+	}
+
+	if f.Value != nil {
+		return f.Value.Validate()
+	}
+	return nil
+}
+
+func (f Filter) Match(m map[string]any) bool {
+	if len(f.Names) > 0 {
+		name := fmt.Sprint(m["name"])
+		var found bool
+		for _, n := range f.Names {
+			if ok, _ := path.Match(n, name); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for k, values := range f.Tags {
+		v, ok := m[record.TagPrefix+k]
+		if !ok || !contains(values, fmt.Sprint(v)) {
+			return false
+		}
+	}
+
+	if f.Value != nil {
+		v, ok := m["value"].(float64)
+		if !ok || !f.Value.Match(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Route sends records to a sink by the value of a record key, ie:
+// `tag.customer`. A route without values is the default route for its
+// key and receives the records no other route of the key claims.
+type Route struct {
+	Key    string
+	Values []string
+}
+
+func (r Route) Validate() error {
+	if r.Key == "" {
+		return fmt.Errorf("route_by requires a %q", "key")
+	}
+	return nil
+}
+
+// Router resolves routes across all sinks of a collector.
+type Router struct {
+	// claimed tracks the values routed explicitly for each key.
+	claimed map[string]map[string]struct{}
+}
+
+func (r *Router) Match(route Route, m map[string]any) bool {
+	v, ok := m[route.Key]
+	if !ok {
+		return len(route.Values) == 0
+	}
+
+	value := fmt.Sprint(v)
+	if len(route.Values) > 0 {
+		return contains(route.Values, value)
+	}
+
+	_, claimed := r.claimed[route.Key][value]
+	return !claimed
+}
+
+func NewRouter(cs map[string]Config) *Router {
+	r := &Router{
+		claimed: make(map[string]map[string]struct{}),
+	}
+
+	for _, c := range cs {
+		if c.RouteBy == nil {
+			continue
+		}
+		if _, ok := r.claimed[c.RouteBy.Key]; !ok {
+			r.claimed[c.RouteBy.Key] = make(map[string]struct{})
+		}
+		for _, v := range c.RouteBy.Values {
+			r.claimed[c.RouteBy.Key][v] = struct{}{}
+		}
+	}
+	return r
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
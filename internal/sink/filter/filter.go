@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"context"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/record"
+)
+
+// MatchFunc reports if a record should be written.
+type MatchFunc func(m map[string]any) bool
+
+// Sinker only writes the records which match to the wrapped sinker.
+type Sinker struct {
+	invoker.Sinker
+
+	match MatchFunc
+}
+
+func (s *Sinker) Write(ctx context.Context, r record.Record) (int, error) {
+	if !s.match(r.Map()) {
+		return 0, nil
+	}
+	return s.Sinker.Write(ctx, r)
+}
+
+func (s *Sinker) Ping(ctx context.Context) error {
+	return invoker.Ping(ctx, s.Sinker)
+}
+
+func New(s invoker.Sinker, match MatchFunc) *Sinker {
+	return &Sinker{
+		Sinker: s,
+		match:  match,
+	}
+}
@@ -0,0 +1,109 @@
+package sink
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFilter_Match(t *testing.T) {
+	min := 1.0
+	max := 10.0
+
+	f := Filter{
+		Names: []string{"core.users.*"},
+		Tags: map[string][]string{
+			"customer": {"google", "amazon"},
+		},
+		Value: &Range{Min: &min, Max: &max},
+	}
+
+	testCases := []struct {
+		name     string
+		m        map[string]any
+		expected bool
+	}{
+		{
+			name:     "match",
+			m:        map[string]any{"name": "core.users.total", "value": 1.0, "tag.customer": "google"},
+			expected: true,
+		},
+		{
+			name: "name_mismatch",
+			m:    map[string]any{"name": "core.orders.total", "value": 1.0, "tag.customer": "google"},
+		},
+		{
+			name: "tag_mismatch",
+			m:    map[string]any{"name": "core.users.total", "value": 1.0, "tag.customer": "microsoft"},
+		},
+		{
+			name: "tag_missing",
+			m:    map[string]any{"name": "core.users.total", "value": 1.0},
+		},
+		{
+			name: "value_out_of_range",
+			m:    map[string]any{"name": "core.users.total", "value": 11.0, "tag.customer": "amazon"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, f.Match(tc.m))
+		})
+	}
+}
+
+func TestRouter_Match(t *testing.T) {
+	google := Route{Key: "tag.customer", Values: []string{"google"}}
+	rest := Route{Key: "tag.customer"}
+
+	r := NewRouter(map[string]Config{
+		"google": {RouteBy: &google},
+		"rest":   {RouteBy: &rest},
+		"all":    {},
+	})
+
+	g := map[string]any{"tag.customer": "google"}
+	a := map[string]any{"tag.customer": "amazon"}
+	untagged := map[string]any{}
+
+	assert.True(t, r.Match(google, g))
+	assert.False(t, r.Match(google, a))
+	assert.False(t, r.Match(google, untagged))
+
+	assert.False(t, r.Match(rest, g))
+	assert.True(t, r.Match(rest, a))
+	assert.True(t, r.Match(rest, untagged))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	min := 2.0
+	max := 1.0
+
+	testCases := []struct {
+		name string
+		c    Config
+		err  string
+	}{
+		{
+			name: "bad_name_pattern",
+			c:    Config{Filter: &Filter{Names: []string{"core["}}},
+			err:  `filter name: "core[": syntax error in pattern`,
+		},
+		{
+			name: "inverted_range",
+			c:    Config{Filter: &Filter{Value: &Range{Min: &min, Max: &max}}},
+			err:  "filter value min: 2 is greater than max: 1",
+		},
+		{
+			name: "route_without_key",
+			c:    Config{RouteBy: &Route{}},
+			err:  `route_by requires a "key"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.EqualError(t, tc.c.Validate(), tc.err)
+		})
+	}
+}
@@ -3,9 +3,11 @@ package http
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"github.com/turbolytics/latte/internal/encoding"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 	"go.uber.org/zap"
 	"io"
@@ -13,6 +15,10 @@ import (
 )
 
 type config struct {
+	// Batch buffers all records written during an invocation and
+	// sends them as a single request on Flush. A batch fails when the
+	// response status is not 2xx, since all of its records are lost.
+	Batch    bool
 	Encoding encoding.Config
 	Headers  map[string]string
 	Method   string
@@ -28,8 +34,10 @@ func WithLogger(l *zap.Logger) Option {
 }
 
 type HTTP struct {
-	config  config
-	encoder encoding.Encoder
+	buf      *bytes.Buffer
+	config   config
+	encoder  encoding.Encoder
+	sinkType sink.Type
 
 	logger *zap.Logger
 }
@@ -39,32 +47,59 @@ func (h *HTTP) Close() error {
 }
 
 func (h *HTTP) Flush(ctx context.Context) error {
-	return nil
+	if !h.config.Batch || h.buf == nil || h.buf.Len() == 0 {
+		return nil
+	}
+
+	defer h.buf.Reset()
+	return h.send(ctx, h.buf)
 }
 
 func (h *HTTP) Type() sink.Type {
-	return sink.TypeHTTP
+	return h.sinkType
 }
 
 func (h *HTTP) Write(ctx context.Context, r record.Record) (int, error) {
+	if h.config.Batch {
+		if h.buf == nil {
+			h.buf = &bytes.Buffer{}
+		}
+		start := h.buf.Len()
+		if err := h.encoder.Init(h.buf); err != nil {
+			return 0, err
+		}
+		if err := h.encoder.Write(r.Map()); err != nil {
+			return 0, retry.Permanent(err)
+		}
+		return h.buf.Len() - start, nil
+	}
+
 	buf := &bytes.Buffer{}
 	if err := h.encoder.Init(buf); err != nil {
 		return 0, nil
 	}
 
 	if err := h.encoder.Write(r.Map()); err != nil {
+		return 0, retry.Permanent(err)
+	}
+
+	n := buf.Len()
+	if err := h.send(ctx, buf); err != nil {
 		return 0, err
 	}
 
-	bs := buf.Bytes()
+	return n, nil
+}
 
-	req, err := http.NewRequest(
+func (h *HTTP) send(ctx context.Context, body io.Reader) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
 		h.config.Method,
 		h.config.URI,
-		buf,
+		body,
 	)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	for k, v := range h.config.Headers {
@@ -73,30 +108,34 @@ func (h *HTTP) Write(ctx context.Context, r record.Record) (int, error) {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
+		return err
 	}
 
 	h.logger.Debug(
 		"http.response",
 		zap.String("name", "http.sink"),
 		zap.Int("response.status_cod", resp.StatusCode),
-		zap.ByteString("resp", body),
+		zap.ByteString("resp", respBody),
 	)
 
-	return len(bs), nil
-}
-
-func NewFromGenericConfig(m map[string]any, opts ...Option) (*HTTP, error) {
-	var conf config
-	if err := mapstructure.Decode(m, &conf); err != nil {
-		return nil, err
+	if h.config.Batch && resp.StatusCode >= http.StatusMultipleChoices {
+		err := fmt.Errorf("http sink received status: %d, body: %q", resp.StatusCode, respBody)
+		// client errors will fail the same way when retried
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return retry.Permanent(err)
+		}
+		return err
 	}
 
+	return nil
+}
+
+func newHTTP(conf config, t sink.Type, opts ...Option) (*HTTP, error) {
 	e, err := encoding.NewEncoder(conf.Encoding)
 
 	if err != nil {
@@ -104,8 +143,9 @@ func NewFromGenericConfig(m map[string]any, opts ...Option) (*HTTP, error) {
 	}
 
 	h := &HTTP{
-		config:  conf,
-		encoder: e,
+		config:   conf,
+		encoder:  e,
+		sinkType: t,
 	}
 	for _, opt := range opts {
 		opt(h)
@@ -113,3 +153,12 @@ func NewFromGenericConfig(m map[string]any, opts ...Option) (*HTTP, error) {
 
 	return h, nil
 }
+
+func NewFromGenericConfig(m map[string]any, opts ...Option) (*HTTP, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	return newHTTP(conf, sink.TypeHTTP, opts...)
+}
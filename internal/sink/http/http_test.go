@@ -0,0 +1,35 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTP_Write_IgnoresStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	h, err := NewFromGenericConfig(
+		map[string]any{
+			"uri":    ts.URL,
+			"method": http.MethodPost,
+			"encoding": map[string]any{
+				"type": "json",
+			},
+		},
+		WithLogger(zap.NewNop()),
+	)
+	assert.NoError(t, err)
+
+	// unbatched writes only log the response
+	_, err = h.Write(context.Background(), &metric.Metric{Name: "m", Value: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Flush(context.Background()))
+}
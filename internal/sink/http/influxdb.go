@@ -0,0 +1,96 @@
+package http
+
+import (
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/encoding"
+	"github.com/turbolytics/latte/internal/encoding/influx"
+	"github.com/turbolytics/latte/internal/sink"
+	"net/http"
+	"net/url"
+)
+
+type influxDBConfig struct {
+	URI       string
+	Org       string
+	Bucket    string
+	Token     string
+	Precision influx.Precision
+}
+
+func (c influxDBConfig) Validate() error {
+	if c.URI == "" {
+		return fmt.Errorf("influxdb sink requires a %q", "uri")
+	}
+	if c.Org == "" {
+		return fmt.Errorf("influxdb sink requires an %q", "org")
+	}
+	if c.Bucket == "" {
+		return fmt.Errorf("influxdb sink requires a %q", "bucket")
+	}
+	return nil
+}
+
+// writeURI builds the InfluxDB v2 write API endpoint:
+// <uri>/api/v2/write?org=<org>&bucket=<bucket>&precision=<precision>
+func (c influxDBConfig) writeURI() (string, error) {
+	u, err := url.Parse(c.URI)
+	if err != nil {
+		return "", err
+	}
+	u = u.JoinPath("api", "v2", "write")
+
+	q := u.Query()
+	q.Set("org", c.Org)
+	q.Set("bucket", c.Bucket)
+	q.Set("precision", string(c.Precision))
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// NewInfluxDBFromGenericConfig is a preset of the HTTP sink for the
+// InfluxDB v2 write API. Records are encoded as line protocol and
+// written as a single batch on each Flush.
+func NewInfluxDBFromGenericConfig(m map[string]any, opts ...Option) (*HTTP, error) {
+	var ic influxDBConfig
+	if err := mapstructure.Decode(m, &ic); err != nil {
+		return nil, err
+	}
+
+	if ic.Precision == "" {
+		ic.Precision = influx.PrecisionNanoseconds
+	}
+
+	if err := ic.Validate(); err != nil {
+		return nil, err
+	}
+
+	uri, err := ic.writeURI()
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{
+		"Content-Type": "text/plain; charset=utf-8",
+		"Accept":       "application/json",
+	}
+	if ic.Token != "" {
+		headers["Authorization"] = fmt.Sprintf("Token %s", ic.Token)
+	}
+
+	conf := config{
+		Batch: true,
+		Encoding: encoding.Config{
+			Type: encoding.TypeInflux,
+			Config: map[string]any{
+				"precision": string(ic.Precision),
+			},
+		},
+		Headers: headers,
+		Method:  http.MethodPost,
+		URI:     uri,
+	}
+
+	return newHTTP(conf, sink.TypeInfluxDB, opts...)
+}
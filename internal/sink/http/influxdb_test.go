@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestInfluxDB_Flush_WritesBatch(t *testing.T) {
+	var reqs []*http.Request
+	var bodies []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, r)
+		bodies = append(bodies, string(bs))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	h, err := NewInfluxDBFromGenericConfig(
+		map[string]any{
+			"uri":       ts.URL,
+			"org":       "turbolytics",
+			"bucket":    "latte",
+			"token":     "secret",
+			"precision": "s",
+		},
+		WithLogger(zap.NewNop()),
+	)
+	assert.NoError(t, err)
+
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, customer := range []string{"amazon", "google"} {
+		_, err := h.Write(context.Background(), &metric.Metric{
+			Name:   "core.users.total",
+			Value:  1,
+			Window: &window,
+			Tags: map[string]string{
+				"customer": customer,
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	// records are only sent on flush
+	assert.Equal(t, 0, len(reqs))
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, 1, len(reqs))
+
+	r := reqs[0]
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "/api/v2/write", r.URL.Path)
+	assert.Equal(t, "turbolytics", r.URL.Query().Get("org"))
+	assert.Equal(t, "latte", r.URL.Query().Get("bucket"))
+	assert.Equal(t, "s", r.URL.Query().Get("precision"))
+	assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+	assert.Equal(t,
+		"core.users.total,customer=amazon value=1 1704067200\n"+
+			"core.users.total,customer=google value=1 1704067200\n",
+		bodies[0],
+	)
+
+	// an empty buffer does not issue a request
+	assert.NoError(t, h.Flush(context.Background()))
+	assert.Equal(t, 1, len(reqs))
+}
+
+func TestInfluxDB_Flush_ErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"code":"unauthorized"}`))
+	}))
+	defer ts.Close()
+
+	h, err := NewInfluxDBFromGenericConfig(
+		map[string]any{
+			"uri":    ts.URL,
+			"org":    "turbolytics",
+			"bucket": "latte",
+		},
+		WithLogger(zap.NewNop()),
+	)
+	assert.NoError(t, err)
+
+	_, err = h.Write(context.Background(), &metric.Metric{Name: "m", Value: 1})
+	assert.NoError(t, err)
+
+	err = h.Flush(context.Background())
+	assert.EqualError(t, err, `http sink received status: 401, body: "{\"code\":\"unauthorized\"}"`)
+}
+
+func TestNewInfluxDBFromGenericConfig_MissingBucket(t *testing.T) {
+	_, err := NewInfluxDBFromGenericConfig(map[string]any{
+		"uri": "http://localhost:8086",
+		"org": "turbolytics",
+	})
+	assert.EqualError(t, err, "influxdb sink requires a \"bucket\"")
+}
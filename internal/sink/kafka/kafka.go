@@ -7,6 +7,7 @@ import (
 	"github.com/segmentio/kafka-go"
 	"github.com/turbolytics/latte/internal/encoding"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 )
 
@@ -43,12 +44,12 @@ func (k *Kafka) Write(ctx context.Context, r record.Record) (int, error) {
 	}
 
 	if err := k.encoder.Write(r.Map()); err != nil {
-		return 0, err
+		return 0, retry.Permanent(err)
 	}
 
 	bs := buf.Bytes()
 
-	err := k.writer.WriteMessages(context.TODO(),
+	err := k.writer.WriteMessages(ctx,
 		kafka.Message{
 			Value: bs,
 		},
@@ -0,0 +1,357 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	KeyName      = "name"
+	KeyWindow    = "window"
+	KeyTimestamp = "timestamp"
+	KeyTags      = "tags"
+)
+
+const defaultTimeout = 10 * time.Second
+
+var defaultKey = []string{KeyName, KeyWindow, KeyTags}
+
+type timeSeriesConfig struct {
+	// TimeField defaults to `timestamp`, MetaField to `tags`.
+	TimeField   string `mapstructure:"time_field"`
+	MetaField   string `mapstructure:"meta_field"`
+	Granularity string
+}
+
+type writeConcernConfig struct {
+	// W is either a number of nodes or a tag set name such as "majority".
+	W       any
+	Journal *bool
+	Timeout string
+}
+
+func (w *writeConcernConfig) writeConcern() (*writeconcern.WriteConcern, error) {
+	wc := &writeconcern.WriteConcern{
+		W:       w.W,
+		Journal: w.Journal,
+	}
+
+	if w.Timeout != "" {
+		d, err := time.ParseDuration(w.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		wc.WTimeout = d
+	}
+
+	return wc, nil
+}
+
+type config struct {
+	URI        string
+	Database   string
+	Collection string
+	// Key lists the document fields records are upserted by.
+	Key          []string
+	TimeSeries   *timeSeriesConfig   `mapstructure:"timeseries"`
+	WriteConcern *writeConcernConfig `mapstructure:"write_concern"`
+	// Timeout bounds connecting to the server on startup.
+	Timeout string
+
+	timeout time.Duration
+}
+
+func (c *config) init() error {
+	if c.Database == "" {
+		return fmt.Errorf("mongodb sink requires a %q", "database")
+	}
+
+	if c.Collection == "" {
+		return fmt.Errorf("mongodb sink requires a %q", "collection")
+	}
+
+	if len(c.Key) == 0 {
+		c.Key = defaultKey
+	}
+
+	c.timeout = defaultTimeout
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return err
+		}
+		c.timeout = d
+	}
+
+	if c.TimeSeries != nil {
+		if c.TimeSeries.TimeField == "" {
+			c.TimeSeries.TimeField = KeyTimestamp
+		}
+		if c.TimeSeries.MetaField == "" {
+			c.TimeSeries.MetaField = KeyTags
+		}
+	}
+
+	return nil
+}
+
+type Option func(*Mongo)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(m *Mongo) {
+		m.logger = l
+	}
+}
+
+// Mongo buffers records and bulk writes them to a collection on each
+// Flush. Records are upserted by the configured key so re-running a
+// window replaces the previous documents. Time series collections do
+// not support upserts, records are inserted instead.
+type Mongo struct {
+	config config
+	client *mongo.Client
+	coll   *mongo.Collection
+	models []mongo.WriteModel
+
+	logger *zap.Logger
+}
+
+func (m *Mongo) Ping(ctx context.Context) error {
+	if m.client == nil {
+		return nil
+	}
+	return m.client.Ping(ctx, readpref.Primary())
+}
+
+func (m *Mongo) Close() error {
+	if m.client == nil {
+		return nil
+	}
+	return m.client.Disconnect(context.Background())
+}
+
+func (m *Mongo) Flush(ctx context.Context) error {
+	if len(m.models) == 0 {
+		return nil
+	}
+
+	defer func() {
+		m.models = nil
+	}()
+
+	res, err := m.coll.BulkWrite(
+		ctx,
+		m.models,
+		options.BulkWrite().SetOrdered(false),
+	)
+	if err != nil {
+		return err
+	}
+
+	m.logger.Debug(
+		"sinks.Mongo.Flush",
+		zap.Int64("inserted", res.InsertedCount),
+		zap.Int64("upserted", res.UpsertedCount),
+		zap.Int64("modified", res.ModifiedCount),
+	)
+
+	return nil
+}
+
+func (m *Mongo) Type() sink.Type {
+	return sink.TypeMongoDB
+}
+
+func (m *Mongo) Write(ctx context.Context, r record.Record) (int, error) {
+	doc := Document(r.Map(), m.tagsKey())
+
+	if m.config.TimeSeries != nil {
+		m.models = append(m.models, mongo.NewInsertOneModel().SetDocument(doc))
+		return 1, nil
+	}
+
+	filter, err := Filter(doc, m.config.Key)
+	if err != nil {
+		return 0, err
+	}
+
+	m.models = append(m.models, mongo.NewReplaceOneModel().
+		SetFilter(filter).
+		SetReplacement(doc).
+		SetUpsert(true),
+	)
+	return 1, nil
+}
+
+// tagsKey is the document field tags are nested under, the meta field
+// of time series collections.
+func (m *Mongo) tagsKey() string {
+	if m.config.TimeSeries != nil {
+		return m.config.TimeSeries.MetaField
+	}
+	return KeyTags
+}
+
+// Document converts a record map to a document. Tags are nested under
+// tagsKey in key order, so documents with the same tags compare equal.
+// A tagsKey key of the record is dropped, the document has a single
+// tags element for Filter to select.
+func Document(m map[string]any, tagsKey string) bson.D {
+	var doc bson.D
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == tagsKey || strings.HasPrefix(k, record.TagPrefix) {
+			continue
+		}
+		v := m[k]
+		if t, ok := v.(*time.Time); ok {
+			if t == nil {
+				v = nil
+			} else {
+				v = *t
+			}
+		}
+		doc = append(doc, bson.E{Key: k, Value: v})
+	}
+
+	tags := record.Tags(m)
+	var tagKeys []string
+	for k := range tags {
+		tagKeys = append(tagKeys, k)
+	}
+	sort.Strings(tagKeys)
+
+	ts := bson.D{}
+	for _, k := range tagKeys {
+		ts = append(ts, bson.E{Key: k, Value: tags[k]})
+	}
+
+	return append(doc, bson.E{Key: tagsKey, Value: ts})
+}
+
+// Filter selects the key fields from a document. Records collected
+// without a window, ie: tick collectors, are keyed by their timestamp
+// instead so each tick upserts its own document.
+func Filter(doc bson.D, key []string) (bson.D, error) {
+	values := doc.Map()
+
+	var filter bson.D
+	for _, k := range key {
+		v, ok := values[k]
+		if !ok {
+			return nil, fmt.Errorf("mongodb sink key field: %q not found in record", k)
+		}
+		if k == KeyWindow && v == nil {
+			if ts, ok := values[KeyTimestamp]; ok {
+				k, v = KeyTimestamp, ts
+			}
+		}
+		filter = append(filter, bson.E{Key: k, Value: v})
+	}
+	return filter, nil
+}
+
+func (m *Mongo) ensureTimeSeries(ctx context.Context, db *mongo.Database) error {
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: m.config.Collection}})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return nil
+	}
+
+	ts := options.TimeSeries().
+		SetTimeField(m.config.TimeSeries.TimeField).
+		SetMetaField(m.config.TimeSeries.MetaField)
+	if m.config.TimeSeries.Granularity != "" {
+		ts.SetGranularity(m.config.TimeSeries.Granularity)
+	}
+
+	m.logger.Info(
+		"sinks.Mongo.ensureTimeSeries",
+		zap.String("msg", "creating time series collection"),
+		zap.String("collection", m.config.Collection),
+	)
+
+	return db.CreateCollection(
+		ctx,
+		m.config.Collection,
+		options.CreateCollection().SetTimeSeriesOptions(ts),
+	)
+}
+
+func NewFromGenericConfig(ctx context.Context, m map[string]any, validate bool, opts ...Option) (*Mongo, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	collOpts := options.Collection()
+	if conf.WriteConcern != nil {
+		wc, err := conf.WriteConcern.writeConcern()
+		if err != nil {
+			return nil, err
+		}
+		collOpts.SetWriteConcern(wc)
+	}
+
+	s := &Mongo{
+		config: conf,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if validate {
+		return s, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, conf.timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(conf.URI))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+
+	db := client.Database(conf.Database)
+	s.client = client
+
+	if conf.TimeSeries != nil {
+		if err := s.ensureTimeSeries(ctx, db); err != nil {
+			client.Disconnect(context.Background())
+			return nil, err
+		}
+	}
+
+	s.coll = db.Collection(conf.Collection, collOpts)
+	return s, nil
+}
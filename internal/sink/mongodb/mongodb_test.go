@@ -0,0 +1,195 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"testing"
+	"time"
+)
+
+func TestDocument_NestsSortedTags(t *testing.T) {
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := Document(map[string]any{
+		"name":         "core.users.total",
+		"value":        1.0,
+		"window":       &window,
+		"tag.customer": "google",
+		"tag.account":  "a1",
+	}, KeyTags)
+
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: "core.users.total"},
+		{Key: "value", Value: 1.0},
+		{Key: "window", Value: window},
+		{Key: "tags", Value: bson.D{
+			{Key: "account", Value: "a1"},
+			{Key: "customer", Value: "google"},
+		}},
+	}, doc)
+}
+
+func TestDocument_SingleTagsElement(t *testing.T) {
+	doc := Document(map[string]any{
+		"name":         "core.users.total",
+		"tags":         map[string]any{"env": "prod"},
+		"tag.customer": "google",
+	}, KeyTags)
+
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: "core.users.total"},
+		{Key: "tags", Value: bson.D{
+			{Key: "customer", Value: "google"},
+		}},
+	}, doc)
+}
+
+func TestMongo_Write_TimeSeriesMetaFieldHoldsTags(t *testing.T) {
+	s, err := NewFromGenericConfig(context.Background(), map[string]any{
+		"database":   "latte",
+		"collection": "metrics",
+		"timeseries": map[string]any{
+			"meta_field": "meta",
+		},
+	}, true)
+	assert.NoError(t, err)
+
+	_, err = s.Write(context.Background(), &metric.Metric{
+		Name: "core.users.total",
+		Tags: map[string]string{"customer": "google"},
+	})
+	assert.NoError(t, err)
+
+	doc := s.models[0].(*mongo.InsertOneModel).Document.(bson.D)
+	assert.Equal(t, bson.D{
+		{Key: "customer", Value: "google"},
+	}, doc.Map()["meta"])
+	assert.NotContains(t, doc.Map(), KeyTags)
+}
+
+func TestFilter(t *testing.T) {
+	doc := bson.D{
+		{Key: "name", Value: "core.users.total"},
+		{Key: "value", Value: 1.0},
+		{Key: "tags", Value: bson.D{}},
+	}
+
+	filter, err := Filter(doc, []string{"name", "tags"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: "core.users.total"},
+		{Key: "tags", Value: bson.D{}},
+	}, filter)
+
+	_, err = Filter(doc, []string{"missing"})
+	assert.EqualError(t, err, `mongodb sink key field: "missing" not found in record`)
+}
+
+func TestFilter_NilWindowUsesTimestamp(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := Document(map[string]any{
+		"name":         "core.users.total",
+		"value":        1.0,
+		"window":       (*time.Time)(nil),
+		"timestamp":    ts,
+		"tag.customer": "google",
+	}, KeyTags)
+
+	filter, err := Filter(doc, defaultKey)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "name", Value: "core.users.total"},
+		{Key: "timestamp", Value: ts},
+		{Key: "tags", Value: bson.D{
+			{Key: "customer", Value: "google"},
+		}},
+	}, filter)
+}
+
+func TestMongo_Write_Models(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config map[string]any
+		model  mongo.WriteModel
+	}{
+		{
+			name: "upsert_by_default",
+			config: map[string]any{
+				"database":   "latte",
+				"collection": "metrics",
+			},
+			model: &mongo.ReplaceOneModel{},
+		},
+		{
+			name: "insert_into_timeseries",
+			config: map[string]any{
+				"database":   "latte",
+				"collection": "metrics",
+				"timeseries": map[string]any{
+					"granularity": "minutes",
+				},
+			},
+			model: &mongo.InsertOneModel{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewFromGenericConfig(context.Background(), tc.config, true)
+			assert.NoError(t, err)
+
+			_, err = s.Write(context.Background(), &metric.Metric{
+				Name: "core.users.total",
+				Tags: map[string]string{"customer": "google"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(s.models))
+			assert.IsType(t, tc.model, s.models[0])
+		})
+	}
+}
+
+func TestNewFromGenericConfig_WriteConcern(t *testing.T) {
+	s, err := NewFromGenericConfig(context.Background(), map[string]any{
+		"database":   "latte",
+		"collection": "metrics",
+		"write_concern": map[string]any{
+			"w":       "majority",
+			"journal": true,
+			"timeout": "5s",
+		},
+	}, true)
+	assert.NoError(t, err)
+
+	wc, err := s.config.WriteConcern.writeConcern()
+	assert.NoError(t, err)
+	assert.Equal(t, "majority", wc.W)
+	assert.True(t, *wc.Journal)
+	assert.Equal(t, 5*time.Second, wc.WTimeout)
+}
+
+func TestNewFromGenericConfig_Timeout(t *testing.T) {
+	s, err := NewFromGenericConfig(context.Background(), map[string]any{
+		"database":   "latte",
+		"collection": "metrics",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, defaultTimeout, s.config.timeout)
+
+	s, err = NewFromGenericConfig(context.Background(), map[string]any{
+		"database":   "latte",
+		"collection": "metrics",
+		"timeout":    "2s",
+	}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, s.config.timeout)
+
+	_, err = NewFromGenericConfig(context.Background(), map[string]any{
+		"database":   "latte",
+		"collection": "metrics",
+		"timeout":    "soon",
+	}, true)
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nats-io/nats.go"
+	"github.com/turbolytics/latte/internal/encoding"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type config struct {
+	URI      string
+	Encoding encoding.Config
+	// Subject may be templated with the record, ie:
+	// latte.{{.Collector}}.{{.Tags.customer}}
+	Subject   string
+	JetStream bool `mapstructure:"jetstream"`
+	// Dedupe sets the JetStream message id to the record uuid so
+	// retried publishes are de-duplicated by the stream.
+	Dedupe bool
+	// Timeout bounds each flush and jetstream publish acknowledgement.
+	Timeout string
+
+	timeout time.Duration
+}
+
+// subjectData is available to the subject template.
+type subjectData struct {
+	Collector string
+	Name      string
+	Type      string
+	Tags      map[string]string
+}
+
+type Option func(*NATS)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(n *NATS) {
+		n.logger = l
+	}
+}
+
+type NATS struct {
+	config  config
+	conn    *nats.Conn
+	encoder encoding.Encoder
+	js      nats.JetStreamContext
+	subject *template.Template
+
+	logger *zap.Logger
+}
+
+func (n *NATS) Close() error {
+	if n.conn == nil {
+		return nil
+	}
+	return n.conn.Drain()
+}
+
+// Flush waits until the server has processed all published messages.
+func (n *NATS) Flush(ctx context.Context) error {
+	if n.config.JetStream {
+		// each jetstream publish is already acknowledged
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.config.timeout)
+	defer cancel()
+	return n.conn.FlushWithContext(ctx)
+}
+
+func (n *NATS) Type() sink.Type {
+	return sink.TypeNATS
+}
+
+func (n *NATS) Write(ctx context.Context, r record.Record) (int, error) {
+	m := r.Map()
+
+	subject, err := n.renderSubject(ctx, m)
+	if err != nil {
+		return 0, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := n.encoder.Init(buf); err != nil {
+		return 0, err
+	}
+
+	if err := n.encoder.Write(m); err != nil {
+		return 0, retry.Permanent(err)
+	}
+
+	bs := buf.Bytes()
+
+	if !n.config.JetStream {
+		return len(bs), n.conn.Publish(subject, bs)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.config.timeout)
+	defer cancel()
+
+	opts := []nats.PubOpt{
+		nats.Context(ctx),
+	}
+	if id, ok := m["uuid"].(string); ok && n.config.Dedupe && id != "" {
+		opts = append(opts, nats.MsgId(id))
+	}
+
+	ack, err := n.js.Publish(subject, bs, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	n.logger.Debug(
+		"sinks.NATS.ack",
+		zap.String("subject", subject),
+		zap.String("stream", ack.Stream),
+		zap.Uint64("sequence", ack.Sequence),
+		zap.Bool("duplicate", ack.Duplicate),
+	)
+
+	return len(bs), nil
+}
+
+func (n *NATS) renderSubject(ctx context.Context, m map[string]any) (string, error) {
+	d := subjectData{
+		Tags: make(map[string]string),
+	}
+	d.Collector, _ = ctx.Value("collector.name").(string)
+	d.Name, _ = m["name"].(string)
+	d.Type = fmt.Sprint(m["type"])
+
+	for k, v := range m {
+		if strings.HasPrefix(k, record.TagPrefix) {
+			d.Tags[strings.TrimPrefix(k, record.TagPrefix)] = fmt.Sprint(v)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := n.subject.Execute(&out, d); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*NATS, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if conf.Subject == "" {
+		return nil, fmt.Errorf("nats sink requires a %q", "subject")
+	}
+
+	if conf.URI == "" {
+		conf.URI = nats.DefaultURL
+	}
+
+	conf.timeout = defaultTimeout
+	if conf.Timeout != "" {
+		d, err := time.ParseDuration(conf.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		conf.timeout = d
+	}
+
+	t, err := template.New("subject").Option("missingkey=zero").Parse(conf.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := encoding.NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &NATS{
+		config:  conf,
+		encoder: e,
+		subject: t,
+		logger:  zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	if !validate {
+		conn, err := nats.Connect(conf.URI)
+		if err != nil {
+			return nil, err
+		}
+		n.conn = conn
+
+		if conf.JetStream {
+			js, err := conn.JetStream()
+			if err != nil {
+				conn.Close()
+				return nil, err
+			}
+			n.js = js
+		}
+	}
+
+	return n, nil
+}
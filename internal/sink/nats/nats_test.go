@@ -0,0 +1,106 @@
+package nats
+
+import (
+	"context"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"testing"
+	"time"
+)
+
+func runServer(t *testing.T) *server.Server {
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	assert.NoError(t, err)
+
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+func TestNATS_Write_TemplatedSubject(t *testing.T) {
+	ns := runServer(t)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync("latte.>")
+	assert.NoError(t, err)
+	assert.NoError(t, nc.Flush())
+
+	n, err := NewFromGenericConfig(map[string]any{
+		"uri":     ns.ClientURL(),
+		"subject": "latte.{{.Collector}}.{{.Tags.customer}}",
+	}, false)
+	assert.NoError(t, err)
+	defer n.Close()
+
+	ctx := context.WithValue(context.Background(), "collector.name", "users")
+	_, err = n.Write(ctx, &metric.Metric{
+		Name: "core.users.total",
+		Tags: map[string]string{"customer": "google"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, n.Flush(ctx))
+
+	msg, err := sub.NextMsg(time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "latte.users.google", msg.Subject)
+	assert.Contains(t, string(msg.Data), `"name":"core.users.total"`)
+}
+
+func TestNATS_Write_JetStreamDedupe(t *testing.T) {
+	ns := runServer(t)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	assert.NoError(t, err)
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     "LATTE",
+		Subjects: []string{"latte.>"},
+	})
+	assert.NoError(t, err)
+
+	n, err := NewFromGenericConfig(map[string]any{
+		"uri":       ns.ClientURL(),
+		"subject":   "latte.{{.Name}}",
+		"jetstream": true,
+		"dedupe":    true,
+	}, false)
+	assert.NoError(t, err)
+	defer n.Close()
+
+	m := &metric.Metric{
+		UUID: "0d7c1c1e-8a1e-4bde-9f26-2b4a4b1b3c11",
+		Name: "core.users.total",
+	}
+
+	// publishing the same metric twice, ie: after a retry
+	for i := 0; i < 2; i++ {
+		_, err = n.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, n.Flush(context.Background()))
+
+	info, err := js.StreamInfo("LATTE")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+}
+
+func TestNewFromGenericConfig_MissingSubject(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{}, true)
+	assert.EqualError(t, err, "nats sink requires a \"subject\"")
+}
@@ -0,0 +1,323 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/obs"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+type Protocol string
+
+const (
+	ProtocolGRPC Protocol = "grpc"
+	ProtocolHTTP Protocol = "http"
+)
+
+type Temporality string
+
+const (
+	TemporalityDelta      Temporality = "delta"
+	TemporalityCumulative Temporality = "cumulative"
+)
+
+type config struct {
+	Protocol    Protocol
+	Endpoint    string
+	URLPath     string `mapstructure:"url_path"`
+	Insecure    bool
+	Headers     map[string]string
+	Timeout     string
+	Temporality Temporality
+
+	timeout time.Duration
+}
+
+func (c *config) init() error {
+	if c.Protocol == "" {
+		c.Protocol = ProtocolGRPC
+	}
+
+	if c.Temporality == "" {
+		c.Temporality = TemporalityDelta
+	}
+
+	switch c.Protocol {
+	case ProtocolGRPC, ProtocolHTTP:
+	default:
+		return fmt.Errorf("otlp protocol: %q not supported", c.Protocol)
+	}
+
+	switch c.Temporality {
+	case TemporalityDelta, TemporalityCumulative:
+	default:
+		return fmt.Errorf("otlp temporality: %q not supported", c.Temporality)
+	}
+
+	if c.Timeout != "" {
+		d, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return err
+		}
+		c.timeout = d
+	}
+
+	return nil
+}
+
+type Option func(*OTLP)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(o *OTLP) {
+		o.logger = l
+	}
+}
+
+// WithExporter overrides the exporter built from the config.
+func WithExporter(e sdkmetric.Exporter) Option {
+	return func(o *OTLP) {
+		o.exporter = e
+	}
+}
+
+// OTLP buffers metrics written during an invocation and exports
+// them as a single OTLP request on Flush.
+type OTLP struct {
+	config   config
+	exporter sdkmetric.Exporter
+	resource *resource.Resource
+	metrics  []*metric.Metric
+
+	logger *zap.Logger
+}
+
+func (o *OTLP) Close() error {
+	if o.exporter == nil {
+		return nil
+	}
+	return o.exporter.Shutdown(context.Background())
+}
+
+func (o *OTLP) Flush(ctx context.Context) error {
+	if len(o.metrics) == 0 {
+		return nil
+	}
+
+	var end *time.Time
+	if e, ok := ctx.Value("window.end").(time.Time); ok {
+		end = &e
+	}
+
+	rm := o.resourceMetrics(end)
+	o.metrics = nil
+
+	o.logger.Debug(
+		"sinks.OTLP.export",
+		zap.String("protocol", string(o.config.Protocol)),
+		zap.String("endpoint", o.config.Endpoint),
+		zap.Int("metrics.count", len(rm.ScopeMetrics[0].Metrics)),
+	)
+
+	return o.exporter.Export(ctx, rm)
+}
+
+func (o *OTLP) Type() sink.Type {
+	return sink.TypeOTLP
+}
+
+func (o *OTLP) Write(ctx context.Context, r record.Record) (int, error) {
+	m, ok := r.(*metric.Metric)
+	if !ok {
+		return 0, retry.Permanent(fmt.Errorf("otlp sink only supports metrics, received: %T", r))
+	}
+
+	switch m.Type {
+	case metric.TypeCount, metric.TypeGauge:
+	default:
+		return 0, retry.Permanent(fmt.Errorf("otlp sink metric type: %q not supported", m.Type))
+	}
+
+	o.metrics = append(o.metrics, m)
+	return 0, nil
+}
+
+// resourceMetrics groups the buffered metrics by name and type. Each
+// metric becomes a single data point whose start time is the window
+// (or the timestamp for tick collectors) and whose time is the window
+// end, when known.
+func (o *OTLP) resourceMetrics(windowEnd *time.Time) *metricdata.ResourceMetrics {
+	type key struct {
+		name string
+		t    metric.Type
+	}
+
+	var keys []key
+	points := make(map[key][]metricdata.DataPoint[float64])
+
+	for _, m := range o.metrics {
+		start := m.Timestamp
+		if m.Window != nil {
+			start = *m.Window
+		}
+
+		end := m.Timestamp
+		if windowEnd != nil {
+			end = *windowEnd
+		}
+
+		k := key{name: m.Name, t: m.Type}
+		if _, found := points[k]; !found {
+			keys = append(keys, k)
+		}
+
+		points[k] = append(points[k], metricdata.DataPoint[float64]{
+			Attributes: tagsToAttributes(m.Tags),
+			StartTime:  start,
+			Time:       end,
+			Value:      m.Value,
+		})
+	}
+
+	temporality := metricdata.DeltaTemporality
+	if o.config.Temporality == TemporalityCumulative {
+		temporality = metricdata.CumulativeTemporality
+	}
+
+	var ms []metricdata.Metrics
+	for _, k := range keys {
+		md := metricdata.Metrics{
+			Name: k.name,
+		}
+
+		switch k.t {
+		case metric.TypeCount:
+			md.Data = metricdata.Sum[float64]{
+				DataPoints:  points[k],
+				Temporality: temporality,
+				IsMonotonic: true,
+			}
+		case metric.TypeGauge:
+			md.Data = metricdata.Gauge[float64]{
+				DataPoints: points[k],
+			}
+		}
+
+		ms = append(ms, md)
+	}
+
+	return &metricdata.ResourceMetrics{
+		Resource: o.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{
+			{
+				Scope: instrumentation.Scope{
+					Name: "latte",
+				},
+				Metrics: ms,
+			},
+		},
+	}
+}
+
+func tagsToAttributes(tags map[string]string) attribute.Set {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]attribute.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, attribute.String(k, tags[k]))
+	}
+	return attribute.NewSet(kvs...)
+}
+
+func newExporter(conf config) (sdkmetric.Exporter, error) {
+	ctx := context.Background()
+
+	switch conf.Protocol {
+	case ProtocolHTTP:
+		var opts []otlpmetrichttp.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(conf.Endpoint))
+		}
+		if conf.URLPath != "" {
+			opts = append(opts, otlpmetrichttp.WithURLPath(conf.URLPath))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(conf.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(conf.Headers))
+		}
+		if conf.timeout > 0 {
+			opts = append(opts, otlpmetrichttp.WithTimeout(conf.timeout))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	default:
+		var opts []otlpmetricgrpc.Option
+		if conf.Endpoint != "" {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(conf.Endpoint))
+		}
+		if conf.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(conf.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(conf.Headers))
+		}
+		if conf.timeout > 0 {
+			opts = append(opts, otlpmetricgrpc.WithTimeout(conf.timeout))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*OTLP, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	res, err := obs.NewResource()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OTLP{
+		config:   conf,
+		resource: res,
+		logger:   zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.exporter == nil && !validate {
+		e, err := newExporter(conf)
+		if err != nil {
+			return nil, err
+		}
+		o.exporter = e
+	}
+
+	return o, nil
+}
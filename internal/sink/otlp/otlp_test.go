@@ -0,0 +1,132 @@
+package otlp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type testExporter struct {
+	sdkmetric.Exporter
+
+	exports []*metricdata.ResourceMetrics
+}
+
+func (te *testExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	te.exports = append(te.exports, rm)
+	return nil
+}
+
+func TestOTLP_Flush_MapsMetricTypes(t *testing.T) {
+	te := &testExporter{}
+	o, err := NewFromGenericConfig(
+		map[string]any{},
+		true,
+		WithExporter(te),
+		WithLogger(zap.NewNop()),
+	)
+	assert.NoError(t, err)
+
+	windowStart := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	ts := time.Date(2024, 1, 1, 2, 0, 5, 0, time.UTC)
+
+	ms := []*metric.Metric{
+		{
+			Name:      "core.users.total",
+			Type:      metric.TypeCount,
+			Value:     3,
+			Tags:      map[string]string{"customer": "google"},
+			Timestamp: ts,
+			Window:    &windowStart,
+		},
+		{
+			Name:      "core.users.total",
+			Type:      metric.TypeCount,
+			Value:     2,
+			Tags:      map[string]string{"customer": "amazon"},
+			Timestamp: ts,
+			Window:    &windowStart,
+		},
+		{
+			Name:      "core.queue.depth",
+			Type:      metric.TypeGauge,
+			Value:     10,
+			Tags:      map[string]string{},
+			Timestamp: ts,
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), "window.end", windowEnd)
+	for _, m := range ms {
+		_, err := o.Write(ctx, m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, o.Flush(ctx))
+	assert.Equal(t, 1, len(te.exports))
+
+	sm := te.exports[0].ScopeMetrics
+	assert.Equal(t, 1, len(sm))
+	assert.Equal(t, "latte", sm[0].Scope.Name)
+	assert.Equal(t, []metricdata.Metrics{
+		{
+			Name: "core.users.total",
+			Data: metricdata.Sum[float64]{
+				Temporality: metricdata.DeltaTemporality,
+				IsMonotonic: true,
+				DataPoints: []metricdata.DataPoint[float64]{
+					{
+						Attributes: attribute.NewSet(attribute.String("customer", "google")),
+						StartTime:  windowStart,
+						Time:       windowEnd,
+						Value:      3,
+					},
+					{
+						Attributes: attribute.NewSet(attribute.String("customer", "amazon")),
+						StartTime:  windowStart,
+						Time:       windowEnd,
+						Value:      2,
+					},
+				},
+			},
+		},
+		{
+			Name: "core.queue.depth",
+			Data: metricdata.Gauge[float64]{
+				DataPoints: []metricdata.DataPoint[float64]{
+					{
+						Attributes: attribute.NewSet(),
+						StartTime:  ts,
+						Time:       windowEnd,
+						Value:      10,
+					},
+				},
+			},
+		},
+	}, sm[0].Metrics)
+
+	// buffer is reset after each flush
+	assert.NoError(t, o.Flush(ctx))
+	assert.Equal(t, 1, len(te.exports))
+}
+
+func TestOTLP_Write_UnsupportedType(t *testing.T) {
+	o, err := NewFromGenericConfig(map[string]any{}, true)
+	assert.NoError(t, err)
+
+	_, err = o.Write(context.Background(), &metric.Metric{Type: "HISTOGRAM"})
+	assert.EqualError(t, err, "otlp sink metric type: \"HISTOGRAM\" not supported")
+}
+
+func TestNewFromGenericConfig_InvalidProtocol(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"protocol": "udp",
+	}, true)
+	assert.EqualError(t, err, "otlp protocol: \"udp\" not supported")
+}
@@ -0,0 +1,184 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPath = "/metrics/latte"
+	defaultTTL  = 15 * time.Minute
+	// reservedPrefix is the subtree served by the admin api.
+	reservedPrefix = "/admin/collectors/"
+)
+
+// reservedPaths are registered by the daemon on the same mux: latte's
+// own telemetry, health checks and the admin api.
+var reservedPaths = []string{
+	"/metrics",
+	"/healthz",
+	"/readyz",
+	"/admin/collectors",
+}
+
+func reserved(path string) bool {
+	for _, p := range reservedPaths {
+		if path == p {
+			return true
+		}
+	}
+	return strings.HasPrefix(path, reservedPrefix)
+}
+
+type config struct {
+	// Path the metrics are served on by the daemon http server.
+	Path string
+	// Namespace is prepended to each metric name.
+	Namespace string
+	// TTL is how long a series is served after it was last collected.
+	TTL string
+
+	ttl time.Duration
+}
+
+func (c *config) init() error {
+	if c.Path == "" {
+		c.Path = defaultPath
+	}
+
+	if !strings.HasPrefix(c.Path, "/") {
+		return fmt.Errorf("prometheus sink path: %q must start with %q", c.Path, "/")
+	}
+
+	if reserved(c.Path) {
+		return fmt.Errorf("prometheus sink path: %q is reserved", c.Path)
+	}
+
+	c.ttl = defaultTTL
+	if c.TTL != "" {
+		d, err := time.ParseDuration(c.TTL)
+		if err != nil {
+			return err
+		}
+		c.ttl = d
+	}
+
+	return nil
+}
+
+type Option func(*Prometheus)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(p *Prometheus) {
+		p.logger = l
+	}
+}
+
+// WithServeMux registers the metrics path on mux instead of the
+// default mux used by the daemon.
+func WithServeMux(mux *http.ServeMux) Option {
+	return func(p *Prometheus) {
+		p.mux = mux
+	}
+}
+
+// Prometheus exposes the latest value of each collected series so
+// they can be scraped. Latte counts are computed per window rather
+// than accumulated, so every series is exposed as a gauge.
+type Prometheus struct {
+	config   config
+	mux      *http.ServeMux
+	registry *Registry
+	samples  []Sample
+
+	logger *zap.Logger
+}
+
+func (p *Prometheus) Close() error {
+	return nil
+}
+
+// Flush publishes the buffered samples, so a scrape never observes a
+// partially written invocation.
+func (p *Prometheus) Flush(ctx context.Context) error {
+	if p.registry != nil {
+		p.registry.Set(p.config.ttl, p.samples...)
+	}
+
+	p.logger.Debug(
+		"sinks.Prometheus.Flush",
+		zap.String("path", p.config.Path),
+		zap.Int("samples", len(p.samples)),
+	)
+
+	p.samples = nil
+	return nil
+}
+
+func (p *Prometheus) Type() sink.Type {
+	return sink.TypePrometheus
+}
+
+func (p *Prometheus) Write(ctx context.Context, r record.Record) (int, error) {
+	m := r.Map()
+
+	name, _ := m["name"].(string)
+	if name == "" {
+		return 0, fmt.Errorf("prometheus sink requires a record %q", "name")
+	}
+
+	value, ok := m["value"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("prometheus sink record value: %v is not numeric", m["value"])
+	}
+
+	if p.config.Namespace != "" {
+		name = p.config.Namespace + "_" + name
+	}
+
+	labels := make(map[string]string)
+	for k, v := range record.Tags(m) {
+		labels[sanitizeLabel(k)] = v
+	}
+
+	p.samples = append(p.samples, Sample{
+		Name:   sanitizeName(name),
+		Labels: labels,
+		Value:  value,
+	})
+	return 1, nil
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*Prometheus, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	p := &Prometheus{
+		config: conf,
+		mux:    http.DefaultServeMux,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if !validate {
+		p.registry = registryFor(p.mux, conf.Path)
+	}
+
+	return p, nil
+}
@@ -0,0 +1,102 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPrometheus_Scrape(t *testing.T) {
+	mux := http.NewServeMux()
+	p, err := NewFromGenericConfig(map[string]any{
+		"path":      "/business",
+		"namespace": "latte",
+	}, false, WithServeMux(mux))
+	assert.NoError(t, err)
+
+	ms := []*metric.Metric{
+		{Name: "core.users.total", Value: 3, Tags: map[string]string{"customer": "google"}},
+		{Name: "core.users.total", Value: 2, Tags: map[string]string{"customer": `"amazon"`}},
+		{Name: "core.orders.total", Value: 1.5},
+	}
+	for _, m := range ms {
+		_, err := p.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	scrape := func() string {
+		resp, err := http.Get(ts.URL + "/business")
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		bs, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		return string(bs)
+	}
+
+	// nothing is exposed until the invocation is flushed
+	assert.Equal(t, "", scrape())
+
+	assert.NoError(t, p.Flush(context.Background()))
+	assert.Equal(t, `# TYPE latte_core_orders_total gauge
+latte_core_orders_total 1.5
+# TYPE latte_core_users_total gauge
+latte_core_users_total{customer="\"amazon\""} 2
+latte_core_users_total{customer="google"} 3
+`, scrape())
+
+	// latest value replaces the previous one
+	_, err = p.Write(context.Background(), &metric.Metric{
+		Name:  "core.orders.total",
+		Value: 4,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, p.Flush(context.Background()))
+	assert.Contains(t, scrape(), "latte_core_orders_total 4\n")
+}
+
+func TestRegistry_Samples_Expire(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry()
+	r.now = func() time.Time { return now }
+
+	r.Set(time.Minute, Sample{Name: "stale"})
+	now = now.Add(30 * time.Second)
+	r.Set(time.Minute, Sample{Name: "fresh"})
+
+	now = now.Add(45 * time.Second)
+	samples := r.Samples()
+	assert.Equal(t, 1, len(samples))
+	assert.Equal(t, "fresh", samples[0].Name)
+}
+
+func TestNewFromGenericConfig_ReservedPath(t *testing.T) {
+	for _, path := range []string{
+		"/metrics",
+		"/healthz",
+		"/readyz",
+		"/admin/collectors",
+		"/admin/collectors/",
+		"/admin/collectors/core.users/pause",
+	} {
+		t.Run(path, func(t *testing.T) {
+			_, err := NewFromGenericConfig(map[string]any{
+				"path": path,
+			}, true)
+			assert.EqualError(t, err, fmt.Sprintf("prometheus sink path: %q is reserved", path))
+		})
+	}
+
+	_, err := NewFromGenericConfig(map[string]any{
+		"path": "/metrics/latte",
+	}, true)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sample is the latest value of a series.
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+
+	expires time.Time
+}
+
+func (s Sample) key() string {
+	var b strings.Builder
+	b.WriteString(s.Name)
+	for _, k := range sortedKeys(s.Labels) {
+		fmt.Fprintf(&b, "\x00%s=%s", k, s.Labels[k])
+	}
+	return b.String()
+}
+
+// Registry keeps the latest sample of each series and serves them in
+// the Prometheus text exposition format. Samples which have not been
+// updated within their ttl are dropped.
+type Registry struct {
+	mu      sync.Mutex
+	samples map[string]Sample
+	now     func() time.Time
+}
+
+// Set stores samples, replacing the previous value of each series.
+func (r *Registry) Set(ttl time.Duration, samples ...Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expires := r.now().Add(ttl)
+	for _, s := range samples {
+		s.expires = expires
+		r.samples[s.key()] = s
+	}
+}
+
+// Samples returns the unexpired samples ordered by name and labels.
+func (r *Registry) Samples() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	var keys []string
+	for k, s := range r.samples {
+		if now.After(s.expires) {
+			delete(r.samples, k)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	samples := make([]Sample, 0, len(keys))
+	for _, k := range keys {
+		samples = append(samples, r.samples[k])
+	}
+	return samples
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	Encode(w, r.Samples())
+}
+
+// Encode writes samples in the text exposition format. Samples must be
+// grouped by name.
+func Encode(w io.Writer, samples []Sample) {
+	var last string
+	for _, s := range samples {
+		if s.Name != last {
+			fmt.Fprintf(w, "# TYPE %s gauge\n", s.Name)
+			last = s.Name
+		}
+
+		w.Write([]byte(s.Name))
+		if len(s.Labels) > 0 {
+			var pairs []string
+			for _, k := range sortedKeys(s.Labels) {
+				pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelValueEscaper.Replace(s.Labels[k])))
+			}
+			fmt.Fprintf(w, "{%s}", strings.Join(pairs, ","))
+		}
+		fmt.Fprintf(w, " %s\n", strconv.FormatFloat(s.Value, 'g', -1, 64))
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		samples: make(map[string]Sample),
+		now:     time.Now,
+	}
+}
+
+var (
+	registriesMu sync.Mutex
+	registries   = make(map[registryKey]*Registry)
+)
+
+type registryKey struct {
+	mux  *http.ServeMux
+	path string
+}
+
+// registryFor returns the registry serving path on mux, registering a
+// new one on first use. Sinks of different collectors configured with
+// the same path share a registry.
+func registryFor(mux *http.ServeMux, path string) *Registry {
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+
+	k := registryKey{mux: mux, path: path}
+	if r, ok := registries[k]; ok {
+		return r
+	}
+
+	r := NewRegistry()
+	mux.Handle(path, r)
+	registries[k] = r
+	return r
+}
+
+// sanitizeName replaces characters which are not valid in metric
+// names with underscores.
+func sanitizeName(s string) string {
+	return sanitize(s, true)
+}
+
+// sanitizeLabel replaces characters which are not valid in label
+// names with underscores.
+func sanitizeLabel(s string) string {
+	return sanitize(s, false)
+}
+
+func sanitize(s string, allowColon bool) string {
+	bs := []byte(s)
+	for i, c := range bs {
+		valid := c == '_' ||
+			(c == ':' && allowColon) ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9' && i > 0)
+		if !valid {
+			bs[i] = '_'
+		}
+	}
+	return string(bs)
+}
+
+var labelValueEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/turbolytics/latte/internal/encoding"
 	"github.com/turbolytics/latte/internal/partition"
 	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
 	"github.com/turbolytics/latte/internal/sink"
 	"go.uber.org/zap"
 	"path"
@@ -71,7 +72,7 @@ func (s *S3) Flush(ctx context.Context) error {
 		zap.String("key", k),
 	)
 
-	_, err = s.uploader.Upload(&s3manager.UploadInput{
+	_, err = s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket: aws.String(s.config.Bucket),
 
 		// Can also use the `filepath` standard library package to modify the
@@ -102,7 +103,7 @@ func (s *S3) Write(ctx context.Context, r record.Record) (int, error) {
 	}
 
 	if err := s.encoder.Write(r.Map()); err != nil {
-		return 0, err
+		return 0, retry.Permanent(err)
 	}
 
 	return 0, nil
@@ -0,0 +1,249 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxPacketSize keeps each datagram below the common ethernet
+// MTU once IP and UDP headers are added.
+const defaultMaxPacketSize = 1432
+
+type Network string
+
+const (
+	NetworkUDP      Network = "udp"
+	NetworkUnixgram Network = "unixgram"
+)
+
+type config struct {
+	Network       Network
+	Address       string
+	Prefix        string
+	DogStatsD     bool `mapstructure:"dogstatsd"`
+	MaxPacketSize int  `mapstructure:"max_packet_size"`
+}
+
+func (c *config) init() error {
+	if c.Network == "" {
+		c.Network = NetworkUDP
+	}
+
+	switch c.Network {
+	case NetworkUDP, NetworkUnixgram:
+	default:
+		return fmt.Errorf("statsd network: %q not supported", c.Network)
+	}
+
+	if c.Address == "" {
+		return fmt.Errorf("statsd address must be set")
+	}
+
+	if c.MaxPacketSize == 0 {
+		c.MaxPacketSize = defaultMaxPacketSize
+	}
+
+	return nil
+}
+
+type Option func(*StatsD)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(s *StatsD) {
+		s.logger = l
+	}
+}
+
+// StatsD buffers one line per metric and sends them on Flush, packing
+// as many lines as fit into each datagram.
+type StatsD struct {
+	config config
+	conn   net.Conn
+	lines  [][]byte
+
+	logger *zap.Logger
+}
+
+func (s *StatsD) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *StatsD) Flush(ctx context.Context) error {
+	defer func() {
+		s.lines = nil
+	}()
+
+	packets := s.packets()
+
+	s.logger.Debug(
+		"sinks.StatsD.flush",
+		zap.String("address", s.config.Address),
+		zap.Int("lines", len(s.lines)),
+		zap.Int("packets", len(packets)),
+	)
+
+	for _, p := range packets {
+		if _, err := s.conn.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StatsD) Type() sink.Type {
+	return sink.TypeStatsD
+}
+
+func (s *StatsD) Write(ctx context.Context, r record.Record) (int, error) {
+	m, ok := r.(*metric.Metric)
+	if !ok {
+		return 0, retry.Permanent(fmt.Errorf("statsd sink only supports metrics, received: %T", r))
+	}
+
+	line, err := s.line(m)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(line) > s.config.MaxPacketSize {
+		return 0, retry.Permanent(fmt.Errorf(
+			"statsd line of %d bytes exceeds max_packet_size: %d",
+			len(line),
+			s.config.MaxPacketSize,
+		))
+	}
+
+	s.lines = append(s.lines, line)
+	return len(line), nil
+}
+
+// line renders a metric using the statsd text format:
+//
+//	<prefix><name>:<value>|<c|g>[|#<key>:<value>,...]
+func (s *StatsD) line(m *metric.Metric) ([]byte, error) {
+	var t string
+	switch m.Type {
+	case metric.TypeCount:
+		t = "c"
+	case metric.TypeGauge:
+		t = "g"
+	default:
+		return nil, retry.Permanent(fmt.Errorf("statsd sink metric type: %q not supported", m.Type))
+	}
+
+	var b bytes.Buffer
+	b.WriteString(sanitize(s.config.Prefix + m.Name))
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatFloat(m.Value, 'f', -1, 64))
+	b.WriteByte('|')
+	b.WriteString(t)
+
+	if s.config.DogStatsD && len(m.Tags) > 0 {
+		keys := make([]string, 0, len(m.Tags))
+		for k := range m.Tags {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteString("|#")
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(sanitizeTag(k))
+			b.WriteByte(':')
+			b.WriteString(sanitizeTag(m.Tags[k]))
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// packets joins buffered lines with newlines, starting a new packet
+// whenever the next line would exceed the max packet size.
+func (s *StatsD) packets() [][]byte {
+	var packets [][]byte
+	var curr []byte
+
+	for _, line := range s.lines {
+		if len(curr) > 0 && len(curr)+1+len(line) > s.config.MaxPacketSize {
+			packets = append(packets, curr)
+			curr = nil
+		}
+		if len(curr) > 0 {
+			curr = append(curr, '\n')
+		}
+		curr = append(curr, line...)
+	}
+
+	if len(curr) > 0 {
+		packets = append(packets, curr)
+	}
+	return packets
+}
+
+var nameReplacer = strings.NewReplacer(
+	":", "_",
+	"|", "_",
+	"@", "_",
+	"\n", "_",
+)
+
+var tagReplacer = strings.NewReplacer(
+	":", "_",
+	"|", "_",
+	",", "_",
+	"\n", "_",
+)
+
+func sanitize(s string) string {
+	return nameReplacer.Replace(s)
+}
+
+func sanitizeTag(s string) string {
+	return tagReplacer.Replace(s)
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*StatsD, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	s := &StatsD{
+		config: conf,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if !validate {
+		conn, err := net.Dial(string(conf.Network), conf.Address)
+		if err != nil {
+			return nil, err
+		}
+		s.conn = conn
+	}
+
+	return s, nil
+}
@@ -0,0 +1,98 @@
+package statsd
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsD_Flush_DogStatsD(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer pc.Close()
+
+	s, err := NewFromGenericConfig(map[string]any{
+		"address":   pc.LocalAddr().String(),
+		"prefix":    "latte.",
+		"dogstatsd": true,
+	}, false)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	ms := []*metric.Metric{
+		{
+			Name:  "core.users.total",
+			Type:  metric.TypeCount,
+			Value: 3,
+			Tags: map[string]string{
+				"env":      "prod",
+				"customer": "google",
+			},
+		},
+		{
+			Name:  "core.queue.depth",
+			Type:  metric.TypeGauge,
+			Value: 1.5,
+		},
+	}
+
+	for _, m := range ms {
+		_, err := s.Write(context.Background(), m)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.Flush(context.Background()))
+
+	buf := make([]byte, 2048)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t,
+		"latte.core.users.total:3|c|#customer:google,env:prod\nlatte.core.queue.depth:1.5|g",
+		string(buf[:n]),
+	)
+}
+
+func TestStatsD_packets_MaxPacketSize(t *testing.T) {
+	s, err := NewFromGenericConfig(map[string]any{
+		"address":         "127.0.0.1:8125",
+		"max_packet_size": 20,
+	}, true)
+	assert.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := s.Write(context.Background(), &metric.Metric{
+			Name:  name,
+			Type:  metric.TypeCount,
+			Value: 100,
+		})
+		assert.NoError(t, err)
+	}
+
+	var packets []string
+	for _, p := range s.packets() {
+		packets = append(packets, string(p))
+	}
+	assert.Equal(t, []string{
+		"a:100|c\nb:100|c",
+		"c:100|c",
+	}, packets)
+}
+
+func TestStatsD_line_WithoutDogStatsDTags(t *testing.T) {
+	s, err := NewFromGenericConfig(map[string]any{
+		"address": "127.0.0.1:8125",
+	}, true)
+	assert.NoError(t, err)
+
+	bs, err := s.line(&metric.Metric{
+		Name:  "core:users",
+		Type:  metric.TypeGauge,
+		Value: 2,
+		Tags:  map[string]string{"env": "prod"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "core_users:2|g", string(bs))
+}
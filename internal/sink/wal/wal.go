@@ -0,0 +1,399 @@
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var meter = otel.Meter("latte-sink-wal")
+
+const segmentExt = ".wal"
+
+// timeKeys are restored to times when records are replayed.
+var timeKeys = []string{"timestamp", "window"}
+
+type replayed map[string]any
+
+func (r replayed) Map() map[string]any {
+	return r
+}
+
+type segment struct {
+	path    string
+	seq     uint64
+	size    int64
+	created time.Time
+}
+
+type Option func(*WAL)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(w *WAL) {
+		w.logger = l
+	}
+}
+
+// WAL persists each flushed batch to disk before writing it to the
+// wrapped sinker. Segments are replayed in order and only removed once
+// the wrapped sinker flushed them, so batches written during an outage
+// are delivered once the sink recovers. Delivery is at least once, a
+// partially delivered segment is replayed in full.
+type WAL struct {
+	invoker.Sinker
+
+	config  sink.Buffer
+	pending []record.Record
+
+	mu       sync.Mutex
+	segments []segment
+	nextSeq  uint64
+
+	registration metric.Registration
+
+	now    func() time.Time
+	logger *zap.Logger
+}
+
+func (w *WAL) Write(ctx context.Context, r record.Record) (int, error) {
+	w.pending = append(w.pending, r)
+	return 0, nil
+}
+
+// Flush appends the pending records to the log, then replays every
+// segment in order. Once appended the records are durable, so only a
+// failure to append is returned. Segments that fail to deliver are
+// logged, reported by the buffered gauges and replayed by the next
+// flush; returning the failure would have the invoker retry the batch
+// and append it again.
+func (w *WAL) Flush(ctx context.Context) error {
+	if len(w.pending) > 0 {
+		if err := w.append(w.pending); err != nil {
+			return err
+		}
+		w.pending = nil
+	}
+
+	if err := w.replay(ctx); err != nil {
+		bytes, age := w.stats()
+		w.logger.Warn(
+			"sinks.WAL.Flush",
+			zap.String("msg", "sink unavailable, records buffered"),
+			zap.String("path", w.config.Path),
+			zap.Int64("buffered.bytes", bytes),
+			zap.Duration("buffered.age", age),
+			zap.Error(err),
+		)
+	}
+	return nil
+}
+
+// Close unregisters the buffer metrics and closes the wrapped sinker.
+func (w *WAL) Close() error {
+	var err error
+	if w.registration != nil {
+		err = w.registration.Unregister()
+	}
+	return errors.Join(err, w.Sinker.Close())
+}
+
+func (w *WAL) Ping(ctx context.Context) error {
+	return invoker.Ping(ctx, w.Sinker)
+}
+
+func (w *WAL) append(rs []record.Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seg := segment{
+		seq:     w.nextSeq,
+		created: w.now(),
+	}
+	seg.path = filepath.Join(w.config.Path, fmt.Sprintf("%020d%s", seg.seq, segmentExt))
+
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, r := range rs {
+		if err := enc.Encode(r.Map()); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	seg.size = fi.Size()
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	w.nextSeq++
+	w.segments = append(w.segments, seg)
+	return w.truncate()
+}
+
+// truncate drops the oldest segments until the log fits in max bytes.
+// The newest segment is always kept.
+func (w *WAL) truncate() error {
+	var total int64
+	for _, s := range w.segments {
+		total += s.size
+	}
+
+	for total > w.config.MaxBytes && len(w.segments) > 1 {
+		oldest := w.segments[0]
+		if err := os.Remove(oldest.path); err != nil {
+			return err
+		}
+		total -= oldest.size
+		w.segments = w.segments[1:]
+
+		w.logger.Error(
+			"sinks.WAL.truncate",
+			zap.String("msg", "max bytes exceeded, dropping oldest segment"),
+			zap.String("segment", oldest.path),
+			zap.Int64("max_bytes", w.config.MaxBytes),
+		)
+	}
+	return nil
+}
+
+func (w *WAL) replay(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if len(w.segments) == 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		seg := w.segments[0]
+		w.mu.Unlock()
+
+		rs, err := readSegment(seg.path)
+		if err != nil {
+			// a corrupt segment is set aside so it does not block
+			// the segments after it.
+			w.logger.Error(
+				"sinks.WAL.replay",
+				zap.String("msg", "corrupt segment"),
+				zap.String("segment", seg.path),
+				zap.Error(err),
+			)
+			if err := os.Rename(seg.path, seg.path+".corrupt"); err != nil {
+				return err
+			}
+			w.pop()
+			continue
+		}
+
+		for _, r := range rs {
+			if _, err := w.Sinker.Write(ctx, r); err != nil {
+				return err
+			}
+		}
+
+		if err := w.Sinker.Flush(ctx); err != nil {
+			return err
+		}
+
+		if err := os.Remove(seg.path); err != nil {
+			return err
+		}
+		w.pop()
+	}
+}
+
+func (w *WAL) pop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.segments = w.segments[1:]
+}
+
+// stats returns the bytes buffered and the age of the oldest segment.
+func (w *WAL) stats() (int64, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var total int64
+	for _, s := range w.segments {
+		total += s.size
+	}
+
+	var age time.Duration
+	if len(w.segments) > 0 {
+		age = w.now().Sub(w.segments[0].created)
+	}
+	return total, age
+}
+
+func (w *WAL) registerMetrics() error {
+	attrs := metric.WithAttributeSet(attribute.NewSet(
+		attribute.String("sink.type", string(w.Type())),
+		attribute.String("wal.path", w.config.Path),
+	))
+
+	bytes, err := meter.Int64ObservableGauge(
+		"sink.wal.buffered.bytes",
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return err
+	}
+
+	age, err := meter.Float64ObservableGauge(
+		"sink.wal.buffered.age",
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return err
+	}
+
+	w.registration, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		b, a := w.stats()
+		o.ObserveInt64(bytes, b, attrs)
+		o.ObserveFloat64(age, a.Seconds(), attrs)
+		return nil
+	}, bytes, age)
+	return err
+}
+
+// load discovers the segments left by a previous process.
+func (w *WAL) load() error {
+	entries, err := os.ReadDir(w.config.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), segmentExt) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+
+		w.segments = append(w.segments, segment{
+			path:    filepath.Join(w.config.Path, e.Name()),
+			seq:     seq,
+			size:    fi.Size(),
+			created: fi.ModTime(),
+		})
+	}
+
+	sort.Slice(w.segments, func(i, j int) bool {
+		return w.segments[i].seq < w.segments[j].seq
+	})
+
+	if len(w.segments) > 0 {
+		w.nextSeq = w.segments[len(w.segments)-1].seq + 1
+	}
+	return nil
+}
+
+func readSegment(path string) ([]record.Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rs []record.Record
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("segment: %q: %w", path, err)
+		}
+		restoreTimes(m)
+		rs = append(rs, replayed(m))
+	}
+	return rs, nil
+}
+
+func restoreTimes(m map[string]any) {
+	for _, k := range timeKeys {
+		s, ok := m[k].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			continue
+		}
+		if k == "window" {
+			m[k] = &t
+		} else {
+			m[k] = t
+		}
+	}
+}
+
+func New(s invoker.Sinker, c sink.Buffer, opts ...Option) (*WAL, error) {
+	c.SetDefaults()
+
+	w := &WAL{
+		Sinker: s,
+		config: c,
+		now:    time.Now,
+		logger: zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if err := os.MkdirAll(c.Path, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+
+	if err := w.registerMetrics(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
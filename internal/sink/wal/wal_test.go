@@ -0,0 +1,195 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/breaker"
+	"github.com/turbolytics/latte/internal/invoker"
+	"github.com/turbolytics/latte/internal/metric"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/retry"
+	"github.com/turbolytics/latte/internal/sink"
+	"go.uber.org/zap"
+	"os"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	pending []record.Record
+	flushed []record.Record
+	err     error
+}
+
+func (ts *testSink) Write(ctx context.Context, r record.Record) (int, error) {
+	ts.pending = append(ts.pending, r)
+	return 0, nil
+}
+
+func (ts *testSink) Flush(ctx context.Context) error {
+	defer func() {
+		ts.pending = nil
+	}()
+	if ts.err != nil {
+		return ts.err
+	}
+	ts.flushed = append(ts.flushed, ts.pending...)
+	return nil
+}
+
+func (ts *testSink) Close() error {
+	return nil
+}
+
+func (ts *testSink) Type() sink.Type {
+	return "tester"
+}
+
+func write(t *testing.T, w *WAL, names ...string) error {
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, n := range names {
+		_, err := w.Write(context.Background(), &metric.Metric{
+			Name:   n,
+			Window: &window,
+		})
+		assert.NoError(t, err)
+	}
+	return w.Flush(context.Background())
+}
+
+func names(rs []record.Record) []string {
+	var ns []string
+	for _, r := range rs {
+		ns = append(ns, r.Map()["name"].(string))
+	}
+	return ns
+}
+
+func TestWAL_ReplaysInOrderAfterOutage(t *testing.T) {
+	dir := t.TempDir()
+	ts := &testSink{err: errors.New("unavailable")}
+
+	w, err := New(ts, sink.Buffer{Path: dir})
+	assert.NoError(t, err)
+
+	assert.NoError(t, write(t, w, "a", "b"))
+	assert.NoError(t, write(t, w, "c"))
+
+	bytes, _ := w.stats()
+	assert.True(t, bytes > 0)
+	assert.Empty(t, ts.flushed)
+
+	// the sink recovers, buffered batches are delivered before the
+	// current one
+	ts.err = nil
+	assert.NoError(t, write(t, w, "d"))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names(ts.flushed))
+
+	window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, &window, ts.flushed[0].Map()["window"])
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWAL_ResumesSegmentsOnRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	down := &testSink{err: errors.New("unavailable")}
+	w, err := New(down, sink.Buffer{Path: dir})
+	assert.NoError(t, err)
+	assert.NoError(t, write(t, w, "a"))
+	assert.NoError(t, write(t, w, "b"))
+	assert.NoError(t, w.Close())
+
+	up := &testSink{}
+	w, err = New(up, sink.Buffer{Path: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), w.nextSeq)
+
+	assert.NoError(t, write(t, w, "c"))
+	assert.Equal(t, []string{"a", "b", "c"}, names(up.flushed))
+}
+
+func TestWAL_MaxBytesDropsOldest(t *testing.T) {
+	dir := t.TempDir()
+	ts := &testSink{err: errors.New("unavailable")}
+
+	w, err := New(ts, sink.Buffer{Path: dir, MaxBytes: 1})
+	assert.NoError(t, err)
+
+	assert.NoError(t, write(t, w, "a"))
+	assert.NoError(t, write(t, w, "b"))
+
+	assert.Equal(t, 1, len(w.segments))
+
+	ts.err = nil
+	assert.NoError(t, w.Flush(context.Background()))
+	assert.Equal(t, []string{"b"}, names(ts.flushed))
+}
+
+type testCollector struct {
+	sinks []invoker.Sink
+}
+
+func (t testCollector) Name() string                             { return "buffered" }
+func (t testCollector) InvocationStrategy() invoker.TypeStrategy { return invoker.TypeStrategyTick }
+func (t testCollector) Sinks() []invoker.Sink                    { return t.sinks }
+func (t testCollector) DeadLetter() *invoker.DeadLetter          { return nil }
+func (t testCollector) Retry() *retry.Policy                     { return nil }
+func (t testCollector) Breaker() *breaker.Breaker                { return nil }
+func (t testCollector) Timeout() time.Duration                   { return 0 }
+func (t testCollector) Schedule() invoker.Schedule               { return nil }
+func (t testCollector) Sourcer() invoker.Sourcer                 { return nil }
+func (t testCollector) SourceGroup() string                      { return "" }
+func (t testCollector) Storer() invoker.Storer                   { return nil }
+func (t testCollector) Transformer() invoker.Transformer         { return nil }
+
+type testResult []record.Record
+
+func (t testResult) Records() []record.Record {
+	return t
+}
+
+func metrics(names ...string) testResult {
+	var rs testResult
+	for _, n := range names {
+		rs = append(rs, &metric.Metric{Name: n})
+	}
+	return rs
+}
+
+func TestWAL_Invoker_RetriesDoNotDuplicate(t *testing.T) {
+	ts := &testSink{err: errors.New("unavailable")}
+	w, err := New(ts, sink.Buffer{Path: t.TempDir()})
+	assert.NoError(t, err)
+
+	b, err := breaker.New(breaker.Config{Failures: 1}, nil)
+	assert.NoError(t, err)
+
+	i, err := invoker.New(testCollector{
+		sinks: []invoker.Sink{{
+			Name:     "buffered",
+			Required: true,
+			Retry: retry.New(retry.Config{MaxAttempts: 3}, retry.WithSleep(
+				func(context.Context, time.Duration) error { return nil },
+			)),
+			Breaker: b,
+			Sinker:  w,
+		}},
+	}, invoker.WithLogger(zap.NewNop()))
+	assert.NoError(t, err)
+
+	// the outage is absorbed by the buffer, the batches are neither
+	// retried nor counted as breaker failures
+	assert.NoError(t, i.Sink(context.Background(), metrics("a", "b")))
+	assert.NoError(t, i.Sink(context.Background(), metrics("c")))
+	assert.Equal(t, 2, len(w.segments))
+	assert.Equal(t, breaker.StateClosed, b.State())
+
+	ts.err = nil
+	assert.NoError(t, i.Sink(context.Background(), metrics("d")))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names(ts.flushed))
+}
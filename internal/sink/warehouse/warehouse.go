@@ -0,0 +1,359 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/marcboeker/go-duckdb"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/mitchellh/mapstructure"
+	"github.com/turbolytics/latte/internal/record"
+	"github.com/turbolytics/latte/internal/sink"
+	"github.com/turbolytics/latte/internal/sink/database"
+	"go.uber.org/zap"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Driver string
+
+const (
+	DriverDuckDB Driver = "duckdb"
+	DriverSQLite Driver = "sqlite3"
+)
+
+type TagsMode string
+
+const (
+	// TagsModeJSON stores tags only in the json `tags` column.
+	TagsModeJSON TagsMode = "json"
+	// TagsModeColumns additionally stores each tag in a `tag_<key>`
+	// column, which is added to the table the first time it is seen.
+	TagsModeColumns TagsMode = "columns"
+)
+
+// columns are the fixed columns of the managed table. Rows are
+// identified by name, window_start and tags.
+var columns = []database.Column{
+	{Name: "uuid", From: "uuid"},
+	{Name: "name", From: "name"},
+	{Name: "value", From: "value"},
+	{Name: "type", From: "type"},
+	{Name: "timestamp", From: "timestamp"},
+	{Name: "window_start", From: "window"},
+	{Name: "tags", From: database.TagsKey},
+}
+
+const windowIndex = 5
+
+var unsafeIdentChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type config struct {
+	Driver Driver
+	Path   string
+	Table  string
+	Tags   TagsMode
+}
+
+func (c *config) init() error {
+	if c.Driver == "" {
+		c.Driver = DriverDuckDB
+	}
+	if c.Table == "" {
+		c.Table = "metrics"
+	}
+	if c.Tags == "" {
+		c.Tags = TagsModeJSON
+	}
+
+	switch c.Driver {
+	case DriverDuckDB, DriverSQLite:
+	default:
+		return fmt.Errorf("warehouse driver: %q not supported", c.Driver)
+	}
+
+	switch c.Tags {
+	case TagsModeJSON, TagsModeColumns:
+	default:
+		return fmt.Errorf("warehouse tags mode: %q not supported", c.Tags)
+	}
+
+	if c.Path == "" {
+		return fmt.Errorf("warehouse sink requires a %q", "path")
+	}
+
+	return nil
+}
+
+type row struct {
+	values []any
+	tags   map[string]any
+}
+
+type Option func(*Warehouse)
+
+func WithLogger(l *zap.Logger) Option {
+	return func(w *Warehouse) {
+		w.logger = l
+	}
+}
+
+// Warehouse writes metrics into a local duckdb or sqlite file. The
+// table is created on startup and writes are idempotent on
+// name, window and tags, so the collected history can be queried
+// directly with SQL.
+type Warehouse struct {
+	config     config
+	db         *sql.DB
+	rows       []row
+	tagColumns map[string]struct{}
+	// tagKeys maps each tag column to the tag key written to it, tag
+	// keys which sanitize to the same column are rejected.
+	tagKeys map[string]string
+
+	logger *zap.Logger
+}
+
+func (w *Warehouse) Close() error {
+	if w.db == nil {
+		return nil
+	}
+	return w.db.Close()
+}
+
+func (w *Warehouse) Flush(ctx context.Context) (err error) {
+	if len(w.rows) == 0 {
+		return nil
+	}
+	defer func() {
+		w.rows = nil
+	}()
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// columns are only cached once committed, a rolled back ALTER
+	// must be retried by the next flush.
+	added := make(map[string]struct{})
+	for _, r := range w.rows {
+		if err = w.ensureTagColumns(ctx, tx, r.tags, added); err != nil {
+			return err
+		}
+
+		stmt, args := w.upsert(r)
+		if _, err = tx.ExecContext(ctx, stmt, args...); err != nil {
+			return err
+		}
+	}
+
+	w.logger.Debug(
+		"sinks.Warehouse.flush",
+		zap.String("driver", string(w.config.Driver)),
+		zap.String("path", w.config.Path),
+		zap.Int("rows", len(w.rows)),
+	)
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	for c := range added {
+		w.tagColumns[c] = struct{}{}
+	}
+	return nil
+}
+
+func (w *Warehouse) Type() sink.Type {
+	return sink.TypeWarehouse
+}
+
+func (w *Warehouse) Write(ctx context.Context, r record.Record) (int, error) {
+	m := r.Map()
+
+	values, err := database.Row(m, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	// tick collectors do not have a window, the timestamp is used
+	// as the window to keep rows identifiable.
+	if t, ok := values[windowIndex].(*time.Time); !ok || t == nil {
+		values[windowIndex] = m["timestamp"]
+	}
+
+	tags := make(map[string]any)
+	if w.config.Tags == TagsModeColumns {
+		for k, v := range m {
+			if !strings.HasPrefix(k, record.TagPrefix) {
+				continue
+			}
+			key := strings.TrimPrefix(k, record.TagPrefix)
+			c := tagColumn(key)
+			if prev, found := w.tagKeys[c]; found && prev != key {
+				return 0, fmt.Errorf("tags: %q and %q both map to column: %q", prev, key, c)
+			}
+			w.tagKeys[c] = key
+			tags[c] = v
+		}
+	}
+
+	w.rows = append(w.rows, row{
+		values: values,
+		tags:   tags,
+	})
+	return 0, nil
+}
+
+func (w *Warehouse) upsert(r row) (string, []any) {
+	var cols, params, updates []string
+	args := append([]any{}, r.values...)
+
+	for _, c := range columns {
+		cols = append(cols, database.QuoteIdentifier(c.Name))
+		params = append(params, "?")
+	}
+
+	var tagCols []string
+	for c := range r.tags {
+		tagCols = append(tagCols, c)
+	}
+	sort.Strings(tagCols)
+
+	for _, c := range tagCols {
+		cols = append(cols, database.QuoteIdentifier(c))
+		params = append(params, "?")
+		args = append(args, r.tags[c])
+	}
+
+	for _, c := range cols {
+		switch c {
+		case `"name"`, `"window_start"`, `"tags"`:
+			continue
+		}
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", c, c))
+	}
+
+	stmt := fmt.Sprintf(
+		`INSERT INTO %s (%s) VALUES (%s) ON CONFLICT ("name", "window_start", "tags") DO UPDATE SET %s`,
+		database.QuoteIdentifier(w.config.Table),
+		strings.Join(cols, ", "),
+		strings.Join(params, ", "),
+		strings.Join(updates, ", "),
+	)
+	return stmt, args
+}
+
+func (w *Warehouse) ensureTagColumns(ctx context.Context, tx *sql.Tx, tags map[string]any, added map[string]struct{}) error {
+	var missing []string
+	for c := range tags {
+		if _, found := w.tagColumns[c]; found {
+			continue
+		}
+		if _, found := added[c]; found {
+			continue
+		}
+		missing = append(missing, c)
+	}
+	sort.Strings(missing)
+
+	for _, c := range missing {
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN %s VARCHAR",
+			database.QuoteIdentifier(w.config.Table),
+			database.QuoteIdentifier(c),
+		))
+		if err != nil {
+			return err
+		}
+		added[c] = struct{}{}
+	}
+	return nil
+}
+
+func (w *Warehouse) migrate(ctx context.Context) error {
+	_, err := w.db.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+	"uuid" VARCHAR,
+	"name" VARCHAR NOT NULL,
+	"value" DOUBLE,
+	"type" VARCHAR,
+	"timestamp" TIMESTAMP,
+	"window_start" TIMESTAMP NOT NULL,
+	"tags" VARCHAR NOT NULL,
+	PRIMARY KEY ("name", "window_start", "tags")
+)`, database.QuoteIdentifier(w.config.Table)))
+	if err != nil {
+		return err
+	}
+
+	// pragma table_info is supported by both duckdb and sqlite
+	rows, err := w.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT name FROM pragma_table_info('%s')",
+		strings.ReplaceAll(w.config.Table, "'", "''"),
+	))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if strings.HasPrefix(name, "tag_") {
+			w.tagColumns[name] = struct{}{}
+		}
+	}
+	return rows.Err()
+}
+
+func tagColumn(key string) string {
+	return "tag_" + unsafeIdentChars.ReplaceAllString(key, "_")
+}
+
+func NewFromGenericConfig(m map[string]any, validate bool, opts ...Option) (*Warehouse, error) {
+	var conf config
+	if err := mapstructure.Decode(m, &conf); err != nil {
+		return nil, err
+	}
+
+	if err := conf.init(); err != nil {
+		return nil, err
+	}
+
+	w := &Warehouse{
+		config:     conf,
+		tagColumns: make(map[string]struct{}),
+		tagKeys:    make(map[string]string),
+		logger:     zap.NewNop(),
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if !validate {
+		db, err := sql.Open(string(conf.Driver), conf.Path)
+		if err != nil {
+			return nil, err
+		}
+		w.db = db
+
+		if err := w.migrate(context.Background()); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
@@ -0,0 +1,151 @@
+package warehouse
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/turbolytics/latte/internal/metric"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestWarehouse_Flush_Idempotent(t *testing.T) {
+	for _, driver := range []Driver{DriverDuckDB, DriverSQLite} {
+		t.Run(string(driver), func(t *testing.T) {
+			dbPath := path.Join(t.TempDir(), "latte.db")
+			w, err := NewFromGenericConfig(map[string]any{
+				"driver": driver,
+				"path":   dbPath,
+				"tags":   "columns",
+			}, false)
+			assert.NoError(t, err)
+
+			window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			ctx := context.Background()
+
+			// re-running the same window replaces the previous value
+			for _, v := range []float64{1, 2} {
+				for _, customer := range []string{"amazon", "google"} {
+					_, err := w.Write(ctx, &metric.Metric{
+						UUID:      "uuid",
+						Name:      "core.users.total",
+						Type:      metric.TypeCount,
+						Value:     v,
+						Timestamp: window.Add(time.Hour),
+						Window:    &window,
+						Tags: map[string]string{
+							"customer": customer,
+						},
+					})
+					assert.NoError(t, err)
+				}
+				assert.NoError(t, w.Flush(ctx))
+			}
+			assert.NoError(t, w.Close())
+
+			db, err := sql.Open(string(driver), dbPath)
+			assert.NoError(t, err)
+			defer db.Close()
+
+			rows, err := db.Query(`SELECT tag_customer, value, tags FROM metrics ORDER BY tag_customer`)
+			assert.NoError(t, err)
+			defer rows.Close()
+
+			type result struct {
+				customer string
+				value    float64
+				tags     string
+			}
+			var results []result
+			for rows.Next() {
+				var r result
+				assert.NoError(t, rows.Scan(&r.customer, &r.value, &r.tags))
+				results = append(results, r)
+			}
+
+			assert.Equal(t, []result{
+				{"amazon", 2, `{"customer":"amazon"}`},
+				{"google", 2, `{"customer":"google"}`},
+			}, results)
+		})
+	}
+}
+
+func TestWarehouse_Write_TickUsesTimestampAsWindow(t *testing.T) {
+	w, err := NewFromGenericConfig(map[string]any{
+		"path": "/tmp/unused.db",
+	}, true)
+	assert.NoError(t, err)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = w.Write(context.Background(), &metric.Metric{
+		Name:      "core.users.total",
+		Timestamp: ts,
+		Tags:      map[string]string{},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ts, w.rows[0].values[windowIndex])
+}
+
+func TestNewFromGenericConfig_InvalidDriver(t *testing.T) {
+	_, err := NewFromGenericConfig(map[string]any{
+		"driver": "mysql",
+		"path":   "/tmp/latte.db",
+	}, true)
+	assert.EqualError(t, err, "warehouse driver: \"mysql\" not supported")
+}
+
+func TestWarehouse_Flush_RollbackRetriesTagColumns(t *testing.T) {
+	for _, driver := range []Driver{DriverDuckDB, DriverSQLite} {
+		t.Run(string(driver), func(t *testing.T) {
+			w, err := NewFromGenericConfig(map[string]any{
+				"driver": driver,
+				"path":   path.Join(t.TempDir(), "latte.db"),
+				"tags":   "columns",
+			}, false)
+			assert.NoError(t, err)
+			defer w.Close()
+			ctx := context.Background()
+
+			// a row without a name fails the transaction after the
+			// tag column was added.
+			w.rows = append(w.rows, row{
+				values: make([]any, len(columns)),
+				tags:   map[string]any{"tag_customer": "amazon"},
+			})
+			assert.Error(t, w.Flush(ctx))
+			assert.NotContains(t, w.tagColumns, "tag_customer")
+
+			window := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			_, err = w.Write(ctx, &metric.Metric{
+				Name:   "core.users.total",
+				Window: &window,
+				Tags:   map[string]string{"customer": "amazon"},
+			})
+			assert.NoError(t, err)
+			assert.NoError(t, w.Flush(ctx))
+			assert.Contains(t, w.tagColumns, "tag_customer")
+		})
+	}
+}
+
+func TestWarehouse_Write_TagColumnCollision(t *testing.T) {
+	w, err := NewFromGenericConfig(map[string]any{
+		"path": "/tmp/unused.db",
+		"tags": "columns",
+	}, true)
+	assert.NoError(t, err)
+
+	_, err = w.Write(context.Background(), &metric.Metric{
+		Name: "core.users.total",
+		Tags: map[string]string{"a-b": "1"},
+	})
+	assert.NoError(t, err)
+
+	_, err = w.Write(context.Background(), &metric.Metric{
+		Name: "core.users.total",
+		Tags: map[string]string{"a_b": "1"},
+	})
+	assert.EqualError(t, err, "tags: \"a-b\" and \"a_b\" both map to column: \"tag_a_b\"")
+}
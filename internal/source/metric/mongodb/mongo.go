@@ -29,6 +29,10 @@ func (m *Mongo) WindowDuration() *time.Duration {
 	return nil
 }
 
+func (m *Mongo) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, readpref.SecondaryPreferred())
+}
+
 func (m Mongo) Type() source.Type {
 	return source.TypeMetricMongoDB
 }
@@ -41,7 +45,7 @@ func (m *Mongo) Source(ctx context.Context) (record.Result, error) {
 
 	col := m.client.Database(m.config.Database).Collection(m.config.Collection)
 	cursor, err := col.Aggregate(
-		context.TODO(),
+		ctx,
 		p,
 	)
 	if err != nil {
@@ -49,7 +53,7 @@ func (m *Mongo) Source(ctx context.Context) (record.Result, error) {
 	}
 
 	var results []bson.M
-	if err = cursor.All(context.TODO(), &results); err != nil {
+	if err = cursor.All(ctx, &results); err != nil {
 		return nil, err
 	}
 
@@ -43,6 +43,10 @@ func (p *Postgres) Source(ctx context.Context) (record.Result, error) {
 	return metricsResult, err
 }
 
+func (p *Postgres) Ping(ctx context.Context) error {
+	return p.db.PingContext(ctx)
+}
+
 func (p *Postgres) Type() source.Type {
 	return source.TypeMetricPostgres
 }
@@ -59,7 +59,12 @@ func (p *Prometheus) promMetrics(ctx context.Context, uri string) (*apiResponse,
 	)
 
 	// make request to prometheus
-	resp, err := http.Get(uri)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +93,7 @@ func (p *Prometheus) applySQL(ctx context.Context, resp *apiResponse) ([]map[str
 		}
 
 		for _, qry := range bootQueries {
-			_, err := execer.ExecContext(context.TODO(), qry, nil)
+			_, err := execer.ExecContext(ctx, qry, nil)
 			if err != nil {
 				return err
 			}
@@ -103,7 +108,7 @@ func (p *Prometheus) applySQL(ctx context.Context, resp *apiResponse) ([]map[str
 	db := sql.OpenDB(connector)
 	defer db.Close()
 
-	_, err = db.Exec(`
+	_, err = db.ExecContext(ctx, `
 CREATE TABLE prom_metrics (
     metric JSON, 
     value VARCHAR 
@@ -113,7 +118,7 @@ CREATE TABLE prom_metrics (
 		return nil, err
 	}
 
-	conn, err := connector.Connect(context.Background())
+	conn, err := connector.Connect(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -133,7 +138,8 @@ CREATE TABLE prom_metrics (
 		// TODO - reevaluate duckdb here,
 		// Check parameterize queries insert. Tried appender API and types
 		// were not working...
-		_, err = db.Exec(
+		_, err = db.ExecContext(
+			ctx,
 			fmt.Sprintf(
 				`INSERT INTO prom_metrics VALUES ('%s', '%s')`,
 				string(bs),
@@ -94,7 +94,7 @@ func (s *S3) Source(ctx context.Context) (record.Result, error) {
 		}
 
 		for _, qry := range bootQueries {
-			_, err := execer.ExecContext(context.TODO(), qry, nil)
+			_, err := execer.ExecContext(ctx, qry, nil)
 			if err != nil {
 				return err
 			}
@@ -114,7 +114,7 @@ func (s *S3) Source(ctx context.Context) (record.Result, error) {
 	if err != nil {
 		return nil, err
 	}
-	_, err = db.Exec(secretDDL)
+	_, err = db.ExecContext(ctx, secretDDL)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +141,7 @@ func (s *S3) Source(ctx context.Context) (record.Result, error) {
 		zap.String("query", b.String()),
 	)
 
-	rows, err := db.Query(b.String())
+	rows, err := db.QueryContext(ctx, b.String())
 	if err != nil {
 		return nil, err
 	}
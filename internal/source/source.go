@@ -16,6 +16,9 @@ const (
 type Config struct {
 	Config map[string]any
 	Type   Type
+	// Group names the system a source queries, ie: a database.
+	// Collectors of a group share its concurrency limit.
+	Group string
 }
 
 func ApplyTemplates(c *Config) error {
@@ -15,7 +15,6 @@ type MemoryStore struct {
 }
 
 func (m *MemoryStore) Close() error {
-	m.Close()
 	return nil
 }
 